@@ -51,7 +51,6 @@ create table if not exists projects (
   description text not null default '',
   owner_id uuid not null references users(id) on delete cascade,
   is_pinned boolean default false,
-  sort_index int not null default 0,
   created_at timestamptz not null default now()
 );
 
@@ -69,10 +68,9 @@ create table if not exists tasks (
   project_id uuid not null references projects(id) on delete cascade,
   title text not null,
   details text not null default '',
-  status text not null default 'backlog', -- backlog | inProgress | blocked | done
+  status text not null default 'backlog', -- a project_statuses.key of the task's project
   assignee_id uuid null references users(id) on delete set null,
   difficulty int not null default 2 check (difficulty between 1 and 5),
-  sort_index int not null default 0,
   created_at timestamptz not null default now()
 );
 
@@ -99,4 +97,411 @@ create index if not exists idx_project_invites_project on project_invites(projec
 
 create index if not exists idx_tasks_project_id on tasks(project_id);
 create index if not exists idx_tasks_project_status on tasks(project_id, status);
-create index if not exists idx_tasks_project_sort on tasks(project_id, sort_index, created_at);
\ No newline at end of file
+
+-- ========= Roadmap =========
+create table if not exists epics (
+  id uuid primary key default gen_random_uuid(),
+  project_id uuid not null references projects(id) on delete cascade,
+  title text not null,
+  description text not null default '',
+  start_date date not null,
+  end_date date not null,
+  sort_index int not null default 0,
+  created_at timestamptz not null default now(),
+  check (end_date >= start_date)
+);
+
+-- epic_id cannot start before depends_on_id is finished
+create table if not exists epic_dependencies (
+  epic_id uuid not null references epics(id) on delete cascade,
+  depends_on_id uuid not null references epics(id) on delete cascade,
+  created_at timestamptz not null default now(),
+  primary key (epic_id, depends_on_id),
+  check (epic_id <> depends_on_id)
+);
+
+alter table tasks add column if not exists epic_id uuid null references epics(id) on delete set null;
+alter table tasks add column if not exists completed_at timestamptz null;
+
+create index if not exists idx_epics_project on epics(project_id, start_date, sort_index);
+create index if not exists idx_tasks_epic on tasks(epic_id);
+
+
+-- ========= Workflow =========
+-- Ordered board columns per project. category lets reports treat custom
+-- columns uniformly: todo | active | done.
+create table if not exists project_statuses (
+  id uuid primary key default gen_random_uuid(),
+  project_id uuid not null references projects(id) on delete cascade,
+  key text not null,
+  name text not null,
+  category text not null check (category in ('todo', 'active', 'done')),
+  sort_index int not null default 0,
+  created_at timestamptz not null default now(),
+  unique (project_id, key)
+);
+
+create index if not exists idx_project_statuses_project on project_statuses(project_id, sort_index);
+
+-- projects created before workflows existed get the original four columns
+insert into project_statuses (project_id, key, name, category, sort_index)
+select p.id, d.key, d.name, d.category, d.sort_index
+from projects p
+cross join (values
+  ('backlog', 'Backlog', 'todo', 0),
+  ('inProgress', 'In Progress', 'active', 1),
+  ('blocked', 'Blocked', 'active', 2),
+  ('done', 'Done', 'done', 3)
+) as d(key, name, category, sort_index)
+where not exists (select 1 from project_statuses s where s.project_id = p.id);
+
+-- Allowed moves between workflow columns. A project with no rows allows every
+-- move. Empty allowed_roles means any member; required_fields names what the
+-- task must carry after the move (reason | assignee | details).
+create table if not exists status_transitions (
+  id uuid primary key default gen_random_uuid(),
+  project_id uuid not null references projects(id) on delete cascade,
+  from_status text not null,
+  to_status text not null,
+  allowed_roles text[] not null default '{}',
+  required_fields text[] not null default '{}',
+  created_at timestamptz not null default now(),
+  unique (project_id, from_status, to_status)
+);
+
+-- why the task is in its current status, e.g. what it is blocked on
+alter table tasks add column if not exists status_reason text null;
+
+-- Optional WIP limits per column. hard rejects writes over the limit, soft
+-- allows them and returns a warning.
+alter table project_statuses add column if not exists wip_limit int null check (wip_limit > 0);
+alter table project_statuses add column if not exists wip_per_assignee int null check (wip_per_assignee > 0);
+alter table project_statuses add column if not exists wip_mode text not null default 'soft' check (wip_mode in ('soft', 'hard'));
+
+
+-- ========= Project tags =========
+-- Tags belong to a user and label the projects on that user's list.
+create table if not exists tags (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references users(id) on delete cascade,
+  name text not null,
+  color text not null default '',
+  created_at timestamptz not null default now()
+);
+
+create unique index if not exists idx_tags_user_name on tags(user_id, lower(name));
+
+create table if not exists project_tags (
+  project_id uuid not null references projects(id) on delete cascade,
+  tag_id uuid not null references tags(id) on delete cascade,
+  created_at timestamptz not null default now(),
+  primary key (project_id, tag_id)
+);
+
+create index if not exists idx_project_tags_tag on project_tags(tag_id);
+
+
+-- ========= Task comments =========
+-- Replies are one level deep: parent_id always points at a top-level comment.
+create table if not exists task_comments (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null references tasks(id) on delete cascade,
+  author_id uuid null references users(id) on delete set null,
+  parent_id uuid null references task_comments(id) on delete cascade,
+  body text not null,
+  created_at timestamptz not null default now(),
+  edited_at timestamptz null,
+  -- set instead of deleting when the comment still has replies
+  deleted_at timestamptz null
+);
+
+create index if not exists idx_task_comments_task on task_comments(task_id, created_at) where parent_id is null;
+create index if not exists idx_task_comments_parent on task_comments(parent_id, created_at);
+
+-- ========= Task attachments =========
+create table if not exists task_attachments (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null references tasks(id) on delete cascade,
+  uploader_id uuid null references users(id) on delete set null,
+  filename text not null,
+  content_type text not null,
+  size_bytes bigint not null check (size_bytes >= 0),
+  -- key in the configured blob store
+  storage_key text not null unique,
+  created_at timestamptz not null default now()
+);
+create index if not exists idx_task_attachments_task on task_attachments(task_id, created_at);
+
+-- ========= Task checklists =========
+create table if not exists task_checklist_items (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null references tasks(id) on delete cascade,
+  body text not null,
+  done boolean not null default false,
+  assignee_id uuid null references users(id) on delete set null,
+  sort_index int not null default 0,
+  created_at timestamptz not null default now()
+);
+create index if not exists idx_task_checklist_items_task on task_checklist_items(task_id, sort_index);
+
+alter table tasks add column if not exists checklist_auto_done boolean not null default false;
+
+-- ========= Task dependencies =========
+-- blocked_id can't be finished while blocker_id is still open
+create table if not exists task_dependencies (
+  blocker_id uuid not null references tasks(id) on delete cascade,
+  blocked_id uuid not null references tasks(id) on delete cascade,
+  created_at timestamptz not null default now(),
+  primary key (blocker_id, blocked_id),
+  check (blocker_id <> blocked_id)
+);
+create index if not exists idx_task_dependencies_blocked on task_dependencies(blocked_id);
+
+-- status tasks are parked in while they have open blockers; null disables
+alter table projects add column if not exists auto_block_status text null;
+-- where an auto-blocked task goes back to once its last blocker closes
+alter table tasks add column if not exists blocked_from text null;
+
+-- ========= Task dates =========
+alter table tasks add column if not exists start_date date null;
+alter table tasks add column if not exists due_at timestamptz null;
+create index if not exists idx_tasks_assignee_due on tasks(assignee_id, due_at) where due_at is not null;
+
+-- ========= Estimates and worklogs =========
+alter table tasks add column if not exists estimate_hours numeric(7,2) null check (estimate_hours >= 0);
+
+create table if not exists task_worklogs (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null references tasks(id) on delete cascade,
+  user_id uuid null references users(id) on delete set null,
+  hours numeric(5,2) not null check (hours > 0 and hours <= 24),
+  work_date date not null,
+  note text not null default '',
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+create index if not exists idx_task_worklogs_task on task_worklogs(task_id, work_date);
+create index if not exists idx_task_worklogs_user on task_worklogs(user_id, work_date);
+
+-- ========= Task keys =========
+-- tasks are numbered per project and shown as "<key>-<number>", e.g. FRG-42
+alter table projects add column if not exists key text null;
+-- next number to hand out; only ever goes up, so numbers are never reused
+alter table projects add column if not exists next_task_number int not null default 1;
+alter table tasks add column if not exists number int null;
+
+do $$
+declare
+    p record;
+    base text;
+    candidate text;
+    n int;
+begin
+    -- projects created before keys existed get one derived from their name
+    for p in select id, name from projects where key is null order by created_at loop
+        base := left(upper(regexp_replace(p.name, '[^A-Za-z0-9]', '', 'g')), 3);
+        if base !~ '^[A-Z][A-Z0-9]+$' then
+            base := 'PRJ';
+        end if;
+        candidate := base;
+        n := 1;
+        while exists (select 1 from projects where key = candidate) loop
+            n := n + 1;
+            candidate := base || n;
+        end loop;
+        update projects set key = candidate where id = p.id;
+    end loop;
+end $$;
+
+-- number existing tasks in creation order
+update tasks t
+set number = r.n
+from (
+    select id, row_number() over (partition by project_id order by created_at, id)
+        + coalesce((select max(number) from tasks x where x.project_id = tasks.project_id), 0) as n
+    from tasks
+    where number is null
+) r
+where t.id = r.id;
+
+update projects p
+set next_task_number = m.next
+from (select project_id, max(number) + 1 as next from tasks group by project_id) m
+where m.project_id = p.id and p.next_task_number < m.next;
+
+alter table projects alter column key set not null;
+alter table tasks alter column number set not null;
+create unique index if not exists idx_projects_key on projects(key);
+create unique index if not exists idx_tasks_project_number on tasks(project_id, number);
+
+-- ========= Task history =========
+-- append-only; task_id has no foreign key so a task's history outlives it
+create table if not exists task_events (
+  id uuid primary key default gen_random_uuid(),
+  -- orders events written in the same transaction (same created_at)
+  seq bigserial not null,
+  project_id uuid not null references projects(id) on delete cascade,
+  task_id uuid not null,
+  actor_id uuid null references users(id) on delete set null,
+  kind text not null check (kind in ('created', 'updated', 'moved', 'deleted')),
+  changes jsonb not null default '{}'::jsonb,
+  created_at timestamptz not null default now()
+);
+create index if not exists idx_task_events_task on task_events(task_id, created_at, seq);
+create index if not exists idx_task_events_project on task_events(project_id, created_at);
+
+-- ========= Task search =========
+alter table tasks add column if not exists search tsvector
+  generated always as (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(details, '')), 'B')
+  ) stored;
+create index if not exists idx_tasks_search on tasks using gin (search);
+
+-- ========= Fractional ranks =========
+-- Tasks (per column) and projects (per owner) are ordered by a base-36 rank
+-- compared bytewise, so a move rewrites only the moved row. This replaces
+-- sort_index, which is still reported but computed from the rank.
+alter table tasks add column if not exists rank text collate "C";
+alter table projects add column if not exists rank text collate "C";
+
+-- one-time migration from sort_index: zero-padded hex is valid rank text
+do $$
+begin
+  if exists (
+    select 1 from information_schema.columns
+    where table_schema = current_schema() and table_name = 'tasks' and column_name = 'sort_index'
+  ) then
+    update tasks t
+    set rank = lpad(to_hex(r.n), 8, '0') || 'i'
+    from (
+      select id, row_number() over (
+        partition by project_id, status order by sort_index, created_at
+      ) as n
+      from tasks
+    ) r
+    where t.id = r.id and t.rank is null;
+    alter table tasks drop column sort_index;
+  end if;
+
+  if exists (
+    select 1 from information_schema.columns
+    where table_schema = current_schema() and table_name = 'projects' and column_name = 'sort_index'
+  ) then
+    update projects p
+    set rank = lpad(to_hex(r.n), 8, '0') || 'i'
+    from (
+      select id, row_number() over (
+        partition by owner_id order by sort_index, created_at desc
+      ) as n
+      from projects
+    ) r
+    where p.id = r.id and p.rank is null;
+    alter table projects drop column sort_index;
+  end if;
+end $$;
+
+alter table tasks alter column rank set not null;
+alter table projects alter column rank set not null;
+
+create index if not exists idx_tasks_column_rank on tasks(project_id, status, rank);
+create index if not exists idx_projects_owner_rank on projects(owner_id, rank);
+
+-- ========= Versions =========
+-- bumped on every edit; sent as the ETag and checked against If-Match
+alter table tasks add column if not exists version int not null default 1;
+alter table projects add column if not exists version int not null default 1;
+
+-- ========= Skill-aware assignment =========
+-- what a task needs; matched against skills(name) and projects_members.roleKey
+alter table tasks add column if not exists required_skills text[] not null default '{}';
+alter table tasks add column if not exists role_key text null;
+
+-- ========= Recurring tasks =========
+-- a template plus an RRULE subset; occurrences are ordinary tasks
+create table if not exists task_series (
+  id uuid primary key default gen_random_uuid(),
+  project_id uuid not null references projects(id) on delete cascade,
+  rrule text not null, -- e.g. FREQ=WEEKLY;INTERVAL=2;COUNT=10
+  starts_on date not null, -- date of occurrence 0
+  next_index int not null default 1,
+  next_on date null, -- null once the rule ran out or the series was stopped
+
+  title text not null,
+  details text not null default '',
+  assignee_id uuid null references users(id) on delete set null,
+  difficulty int not null default 2 check (difficulty between 1 and 5),
+  epic_id uuid null references epics(id) on delete set null,
+  estimate_hours numeric(7,2) null check (estimate_hours >= 0),
+  required_skills text[] not null default '{}',
+  role_key text null,
+
+  created_by uuid null references users(id) on delete set null,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+create index if not exists idx_task_series_next on task_series(next_on) where next_on is not null;
+
+alter table tasks add column if not exists series_id uuid null references task_series(id) on delete set null;
+alter table tasks add column if not exists occurrence_on date null;
+create index if not exists idx_tasks_series on tasks(series_id, occurrence_on) where series_id is not null;
+
+-- ========= Watchers, mentions and notifications =========
+create table if not exists task_watchers (
+  task_id uuid not null references tasks(id) on delete cascade,
+  user_id uuid not null references users(id) on delete cascade,
+  reason text not null default 'manual' check (reason in ('manual', 'assigned', 'commented')),
+  created_at timestamptz not null default now(),
+  primary key (task_id, user_id)
+);
+create index if not exists idx_task_watchers_user on task_watchers(user_id);
+
+-- @username in a task's details (comment_id null) or in one of its comments
+create table if not exists task_mentions (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null references tasks(id) on delete cascade,
+  comment_id uuid null references task_comments(id) on delete cascade,
+  user_id uuid not null references users(id) on delete cascade,
+  mentioned_by uuid null references users(id) on delete set null,
+  created_at timestamptz not null default now()
+);
+create index if not exists idx_task_mentions_task on task_mentions(task_id, comment_id);
+create index if not exists idx_task_mentions_user on task_mentions(user_id, created_at desc);
+
+-- task_id has no foreign key so "task deleted" notifications outlive the task
+create table if not exists notifications (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references users(id) on delete cascade,
+  project_id uuid not null references projects(id) on delete cascade,
+  task_id uuid not null,
+  kind text not null check (kind in ('task_event', 'comment', 'mention')),
+  event_id uuid null references task_events(id) on delete cascade,
+  comment_id uuid null references task_comments(id) on delete cascade,
+  actor_id uuid null references users(id) on delete set null,
+  read_at timestamptz null,
+  created_at timestamptz not null default now()
+);
+create index if not exists idx_notifications_user on notifications(user_id, created_at desc);
+create index if not exists idx_notifications_unread on notifications(user_id) where read_at is null;
+
+-- ========= Trash =========
+-- deleted tasks keep their row until restored or purged after the retention
+alter table tasks add column if not exists deleted_at timestamptz null;
+alter table tasks add column if not exists deleted_by uuid null references users(id) on delete set null;
+create index if not exists idx_tasks_trash on tasks(project_id, deleted_at desc) where deleted_at is not null;
+
+do $$
+begin
+  if not exists (
+    select 1 from pg_constraint
+    where conname = 'task_events_kind_check'
+      and pg_get_constraintdef(oid) like '%restored%'
+  ) then
+    alter table task_events drop constraint if exists task_events_kind_check;
+    alter table task_events add constraint task_events_kind_check
+      check (kind in ('created', 'updated', 'moved', 'deleted', 'restored'));
+  end if;
+end $$;
+
+-- ========= My work =========
+create index if not exists idx_tasks_assignee_status on tasks(assignee_id, status);
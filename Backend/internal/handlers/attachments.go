@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"forge-api/internal/storage"
+)
+
+// ========= Attachment DTOs (responses) =========
+type Attachment struct {
+	ID               string  `json:"id"`
+	TaskID           string  `json:"task_id"`
+	UploaderID       *string `json:"uploader_id"`
+	UploaderUsername *string `json:"uploader_username"`
+	Filename         string  `json:"filename"`
+	ContentType      string  `json:"content_type"`
+	SizeBytes        int64   `json:"size_bytes"`
+	CreatedAt        string  `json:"created_at"`
+}
+
+// DefaultMaxAttachmentBytes applies when the handler isn't given a limit.
+const DefaultMaxAttachmentBytes = 25 << 20
+
+// allowedAttachmentTypes is checked against the sniffed content type, so a
+// renamed executable can't pass as a PNG.
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":        true,
+	"image/jpeg":       true,
+	"image/gif":        true,
+	"image/webp":       true,
+	"application/pdf":  true,
+	"application/zip":  true,
+	"text/plain":       true,
+	"text/csv":         true,
+	"text/markdown":    true,
+	"application/json": true,
+}
+
+// textSubtypes may refine a sniffed text/plain, which is all the sniffer can
+// say about CSV, Markdown or JSON.
+var textSubtypes = map[string]bool{
+	"text/csv":         true,
+	"text/markdown":    true,
+	"application/json": true,
+}
+
+const attachmentColumns = `
+	a.id::text,
+	a.task_id::text,
+	a.uploader_id::text,
+	up.username,
+	a.filename,
+	a.content_type,
+	a.size_bytes,
+	a.created_at
+`
+
+func scanAttachment(row pgx.Row, a *Attachment) error {
+	var createdAt time.Time
+	if err := row.Scan(
+		&a.ID,
+		&a.TaskID,
+		&a.UploaderID,
+		&a.UploaderUsername,
+		&a.Filename,
+		&a.ContentType,
+		&a.SizeBytes,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+	a.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (h *Handler) maxAttachmentBytes() int64 {
+	if h.MaxAttachmentBytes > 0 {
+		return h.MaxAttachmentBytes
+	}
+	return DefaultMaxAttachmentBytes
+}
+
+// cleanFilename keeps the base name of what the client sent, without control
+// characters, so it is safe to echo back in Content-Disposition.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if r := []rune(name); len(r) > 255 {
+		name = string(r[len(r)-255:])
+	}
+	return name
+}
+
+// attachmentType sniffs the upload's first bytes and returns the media type
+// to store, or "" when it isn't allowed.
+func attachmentType(head []byte, declared string) string {
+	sniffed, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return ""
+	}
+	if sniffed == "text/plain" {
+		if d, _, err := mime.ParseMediaType(declared); err == nil && textSubtypes[d] {
+			sniffed = d
+		}
+	}
+	if !allowedAttachmentTypes[sniffed] {
+		return ""
+	}
+	return sniffed
+}
+
+func attachmentKey(taskID uuid.UUID, id uuid.UUID) string {
+	return "attachments/" + taskID.String() + "/" + id.String()
+}
+
+// attachmentKeys lists the blobs behind a task's attachments, or a whole
+// project's when taskID is nil. Read them before deleting the rows: the
+// cascade removes the metadata but not the stored objects.
+func attachmentKeys(ctx context.Context, q dbtx, projectID uuid.UUID, taskID *uuid.UUID) ([]string, error) {
+	rows, err := q.Query(ctx, `
+		select a.storage_key
+		from task_attachments a
+		join tasks t on t.id = a.task_id
+		where t.project_id = $1
+			and ($2::uuid is null or t.id = $2)
+	`, projectID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// removeBlobs deletes stored objects after their rows are gone. Failures only
+// leave orphans behind, so they are logged rather than returned.
+func (h *Handler) removeBlobs(keys []string) {
+	if h.Blobs == nil || len(keys) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, k := range keys {
+		if err := h.Blobs.Delete(ctx, k); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("attachments: delete blob %s: %v", k, err)
+		}
+	}
+}
+
+func (h *Handler) ListAttachments(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+attachmentColumns+`
+		from task_attachments a
+		left join users up on up.id = a.uploader_id
+		where a.task_id = $1
+		order by a.created_at asc, a.id asc
+	`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	out := make([]Attachment, 0)
+	for rows.Next() {
+		var a Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attachments": out})
+}
+
+// UploadAttachment takes a multipart form with a single "file" field.
+func (h *Handler) UploadAttachment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	if h.Blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "attachments are not configured"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		cancel()
+		return
+	}
+	err := taskInProject(ctx, h.DB, projectID, taskID)
+	cancel()
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself.
+	limit := h.maxAttachmentBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "max_bytes": limit})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+		return
+	}
+	if fh.Size > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large", "max_bytes": limit})
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	contentType := attachmentType(head[:n], fh.Header.Get("Content-Type"))
+	if contentType == "" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "file type not allowed"})
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	id := uuid.New()
+	key := attachmentKey(taskID, id)
+
+	putCtx, putCancel := contextTimeout(c, 5*time.Minute)
+	defer putCancel()
+	if err := h.Blobs.Put(putCtx, key, f, fh.Size, contentType); err != nil {
+		log.Printf("attachments: put blob %s: %v", key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "storage error"})
+		return
+	}
+
+	ctx, cancel = contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	// The task may have been deleted while the upload ran; the foreign key
+	// catches that and the blob is dropped again.
+	var out Attachment
+	err = scanAttachment(h.DB.QueryRow(ctx, `
+		with inserted as (
+			insert into task_attachments (id, task_id, uploader_id, filename, content_type, size_bytes, storage_key)
+			select $1, t.id, $3::uuid, $4, $5, $6, $7
+			from tasks t
+			where t.id = $2 and t.deleted_at is null
+			returning *
+		)
+		select `+attachmentColumns+`
+		from inserted a
+		left join users up on up.id = a.uploader_id
+	`, id, taskID, uid, cleanFilename(fh.Filename), contentType, fh.Size, key), &out)
+	if err != nil {
+		h.removeBlobs([]string{key})
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// DownloadAttachment streams the stored file back with its original name.
+func (h *Handler) DownloadAttachment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	attachmentID, ok := parseUUIDParam(c, "attachmentId", "attachment")
+	if !ok {
+		return
+	}
+
+	if h.Blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "attachments are not configured"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var filename, contentType, key string
+	var size int64
+	err := h.DB.QueryRow(ctx, `
+		select a.filename, a.content_type, a.size_bytes, a.storage_key
+		from task_attachments a
+		join tasks t on t.id = a.task_id
+		where a.id = $1 and a.task_id = $2 and t.project_id = $3
+			and t.deleted_at is null
+	`, attachmentID, taskID, projectID).Scan(&filename, &contentType, &size, &key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// The body outlives the metadata lookup's timeout, so it gets the
+	// request's own context.
+	body, err := h.Blobs.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		log.Printf("attachments: get blob %s: %v", key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "storage error"})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, size, contentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=0",
+	})
+}
+
+// DeleteAttachment is open to the uploader and the project owner.
+func (h *Handler) DeleteAttachment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	attachmentID, ok := parseUUIDParam(c, "attachmentId", "attachment")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var key string
+	var allowed bool
+	err := h.DB.QueryRow(ctx, `
+		select a.storage_key, (a.uploader_id = $4::uuid or p.owner_id = $4::uuid)
+		from task_attachments a
+		join tasks t on t.id = a.task_id
+		join projects p on p.id = t.project_id
+		where a.id = $1 and a.task_id = $2 and t.project_id = $3
+			and t.deleted_at is null
+	`, attachmentID, taskID, projectID, uid).Scan(&key, &allowed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the uploader or project owner can delete"})
+		return
+	}
+
+	if _, err := h.DB.Exec(ctx, `delete from task_attachments where id = $1`, attachmentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	h.removeBlobs([]string{key})
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxBatchOps = 100
+
+const (
+	batchOpStatus     = "status"
+	batchOpAssign     = "assign"
+	batchOpDifficulty = "difficulty"
+	batchOpDelete     = "delete"
+	batchOpMove       = "move"
+)
+
+// ========= Batch DTOs (responses) =========
+type BatchResult struct {
+	Index    int          `json:"index"`
+	Op       string       `json:"op"`
+	TaskID   string       `json:"task_id"`
+	OK       bool         `json:"ok"`
+	Status   int          `json:"status"` // HTTP status the op would have got on its own
+	Task     *Task        `json:"task,omitempty"`
+	Warnings []WipWarning `json:"warnings,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Detail   gin.H        `json:"detail,omitempty"`
+}
+
+type batchTasksResp struct {
+	Atomic  bool          `json:"atomic"`
+	Applied int           `json:"applied"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
+// ========= Requests =========
+type batchTasksReq struct {
+	// Atomic (the default) applies every op or none; false applies what it
+	// can and reports the rest.
+	Atomic *bool        `json:"atomic"`
+	Ops    []batchOpReq `json:"ops"`
+}
+
+type batchOpReq struct {
+	Op         string  `json:"op"`
+	TaskID     string  `json:"task_id"`
+	Status     *string `json:"status"`      // status, move
+	Reason     *string `json:"reason"`      // status, move
+	SortIndex  *int    `json:"sort_index"`  // move
+	AfterID    *string `json:"after_id"`    // move
+	BeforeID   *string `json:"before_id"`   // move
+	AssigneeID *string `json:"assignee_id"` // assign; null or "" unassigns
+	Difficulty *int    `json:"difficulty"`  // difficulty
+}
+
+// batchOp is a validated batchOpReq. Change is unused for deletes.
+type batchOp struct {
+	Op     string
+	TaskID uuid.UUID
+	Change taskChange
+}
+
+// parse validates one op without touching the database.
+func (r batchOpReq) parse() (batchOp, error) {
+	op := batchOp{Op: strings.TrimSpace(r.Op)}
+
+	id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(r.TaskID)))
+	if err != nil {
+		return op, newAPIError(http.StatusBadRequest, "invalid task id")
+	}
+	op.TaskID = id
+
+	reason := func() {
+		if r.Reason != nil {
+			op.Change.ReasonSet = true
+			if v := strings.TrimSpace(*r.Reason); v != "" {
+				op.Change.Reason = &v
+			}
+		}
+	}
+
+	switch op.Op {
+	case batchOpStatus:
+		if r.Status == nil || strings.TrimSpace(*r.Status) == "" {
+			return op, newAPIError(http.StatusBadRequest, "missing status")
+		}
+		st := strings.TrimSpace(*r.Status)
+		op.Change.Status = &st
+		reason()
+
+	case batchOpMove:
+		place, err := parseRankPlace(r.SortIndex, r.AfterID, r.BeforeID)
+		if err != nil {
+			return op, err
+		}
+		if !place.isSet() {
+			return op, newAPIError(http.StatusBadRequest, "missing sort_index, after_id or before_id")
+		}
+		op.Change.Place = place
+		if r.Status != nil {
+			st := strings.TrimSpace(*r.Status)
+			op.Change.Status = &st
+		}
+		reason()
+
+	case batchOpAssign:
+		op.Change.AssigneeSet = true
+		if r.AssigneeID != nil && strings.TrimSpace(*r.AssigneeID) != "" {
+			a, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*r.AssigneeID)))
+			if err != nil {
+				return op, newAPIError(http.StatusBadRequest, "invalid assignee id")
+			}
+			as := a.String()
+			op.Change.AssigneeID = &as
+		}
+
+	case batchOpDifficulty:
+		if r.Difficulty == nil {
+			return op, newAPIError(http.StatusBadRequest, "missing difficulty")
+		}
+		if *r.Difficulty < 1 || *r.Difficulty > 5 {
+			return op, newAPIError(http.StatusBadRequest, "invalid difficulty")
+		}
+		op.Change.Difficulty = r.Difficulty
+
+	case batchOpDelete:
+
+	default:
+		return op, newAPIError(http.StatusBadRequest, "unknown op")
+	}
+
+	return op, nil
+}
+
+// fail fills in a result from an error returned by an op.
+func (r *BatchResult) fail(err error) {
+	r.OK = false
+	r.Task = nil
+	r.Warnings = nil
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		r.Status = http.StatusInternalServerError
+		r.Error = "server error"
+		return
+	}
+	r.Status = apiErr.Status
+	r.Error, _ = apiErr.Body["error"].(string)
+	for k, v := range apiErr.Body {
+		if k == "error" {
+			continue
+		}
+		if r.Detail == nil {
+			r.Detail = gin.H{}
+		}
+		r.Detail[k] = v
+	}
+}
+
+// BatchTasks applies a list of task operations in one transaction. Every op
+// is validated up front; a malformed op rejects the whole batch. Ops then
+// run in order, each in its own savepoint, with the same rules as the
+// single-task endpoints.
+func (h *Handler) BatchTasks(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	var req batchTasksReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	if len(req.Ops) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing ops"})
+		return
+	}
+	if len(req.Ops) > maxBatchOps {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many ops", "max": maxBatchOps})
+		return
+	}
+	atomic := req.Atomic == nil || *req.Atomic
+
+	ops := make([]batchOp, len(req.Ops))
+	results := make([]BatchResult, len(req.Ops))
+	invalid := 0
+	for i, r := range req.Ops {
+		results[i] = BatchResult{Index: i, Op: strings.TrimSpace(r.Op), TaskID: strings.TrimSpace(r.TaskID)}
+		op, err := r.parse()
+		if err != nil {
+			results[i].fail(err)
+			invalid++
+			continue
+		}
+		ops[i] = op
+		results[i].TaskID = op.TaskID.String()
+	}
+	if invalid > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ops", "results": results})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 20*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	failed := -1
+	for i, op := range ops {
+		res := &results[i]
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+
+		if op.Op == batchOpDelete {
+			_, _, err = deleteTask(ctx, sp, uid, projectID, op.TaskID)
+		} else {
+			var out Task
+			out, res.Warnings, err = applyTaskChange(ctx, sp, uid, projectID, op.TaskID, op.Change)
+			res.Task = &out
+		}
+		if err == nil {
+			err = sp.Commit(ctx)
+		}
+		if err != nil {
+			_ = sp.Rollback(ctx)
+			res.fail(err)
+			if atomic {
+				failed = i
+				break
+			}
+			continue
+		}
+
+		res.OK = true
+		res.Status = http.StatusOK
+	}
+
+	if failed >= 0 {
+		// Nothing is kept: earlier ops are undone and later ones never ran.
+		for i := range results {
+			switch {
+			case i < failed:
+				results[i] = BatchResult{Index: i, Op: results[i].Op, TaskID: results[i].TaskID,
+					Status: http.StatusFailedDependency, Error: "rolled back"}
+			case i > failed:
+				results[i].Status = http.StatusFailedDependency
+				results[i].Error = "not attempted"
+			}
+		}
+		c.JSON(results[failed].Status, gin.H{
+			"error":   "batch failed",
+			"index":   failed,
+			"atomic":  true,
+			"results": results,
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	resp := batchTasksResp{Atomic: atomic, Results: results}
+	for _, r := range results {
+		if r.OK {
+			resp.Applied++
+		} else {
+			resp.Failed++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
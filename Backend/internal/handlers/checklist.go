@@ -0,0 +1,632 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Checklist DTOs (responses) =========
+type ChecklistItem struct {
+	ID               string  `json:"id"`
+	TaskID           string  `json:"task_id"`
+	Text             string  `json:"text"`
+	Done             bool    `json:"done"`
+	AssigneeID       *string `json:"assignee_id"`
+	AssigneeUsername *string `json:"assignee_username"`
+	SortIndex        int     `json:"sort_index"`
+	CreatedAt        string  `json:"created_at"`
+}
+
+// checklistWriteResp carries the parent task too, so clients can refresh its
+// progress (and status, when the last check moved it to done).
+type checklistWriteResp struct {
+	Item      *ChecklistItem `json:"item,omitempty"`
+	Task      Task           `json:"task"`
+	AutoMoved bool           `json:"auto_moved"`
+	// AutoMoveBlocked is the rule that kept a completed task where it was.
+	AutoMoveBlocked gin.H `json:"auto_move_blocked,omitempty"`
+}
+
+// ========= Requests =========
+type createChecklistItemReq struct {
+	Text       string  `json:"text"`
+	AssigneeID *string `json:"assignee_id"`
+	SortIndex  *int    `json:"sort_index"`
+}
+
+// updateChecklistItemPatch is a merge patch, like updateTaskPatch.
+type updateChecklistItemPatch struct {
+	Text       patchField[string] `json:"text"`
+	Done       patchField[bool]   `json:"done"`
+	AssigneeID patchField[string] `json:"assignee_id"`
+}
+
+type reorderChecklistReq struct {
+	ItemIDs []string `json:"item_ids"`
+}
+
+const maxChecklistText = 500
+
+const checklistColumns = `
+	ci.id::text,
+	ci.task_id::text,
+	ci.body,
+	ci.done,
+	ci.assignee_id::text,
+	cu.username,
+	ci.sort_index,
+	ci.created_at
+`
+
+func scanChecklistItem(row pgx.Row, it *ChecklistItem) error {
+	var createdAt time.Time
+	if err := row.Scan(
+		&it.ID,
+		&it.TaskID,
+		&it.Text,
+		&it.Done,
+		&it.AssigneeID,
+		&it.AssigneeUsername,
+		&it.SortIndex,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+	it.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+func normalizeChecklistText(s string) (string, bool) {
+	text := strings.TrimSpace(s)
+	return text, text != "" && len([]rune(text)) <= maxChecklistText
+}
+
+// lockTaskForChecklist checks the task belongs to the project and locks it,
+// so concurrent checklist edits renumber one at a time.
+func lockTaskForChecklist(ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID) error {
+	var id string
+	err := tx.QueryRow(ctx, `
+		select id::text from tasks
+		where id = $1 and project_id = $2 and deleted_at is null
+		for update
+	`, taskID, projectID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return newAPIError(http.StatusNotFound, "task not found")
+	}
+	return err
+}
+
+// autoCompleteTask moves the task to the workflow's done column when it has
+// opted in and every checklist item is checked. The move goes through the
+// normal task rules in a savepoint; if a transition rule or hard WIP limit
+// refuses it, the checklist write still succeeds and the refusal is returned.
+func autoCompleteTask(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID) (bool, gin.H, error) {
+	var enabled bool
+	var status string
+	var done, total int
+	if err := tx.QueryRow(ctx, `
+		select t.checklist_auto_done, t.status,
+			(select count(*) from task_checklist_items ci where ci.task_id = t.id and ci.done),
+			(select count(*) from task_checklist_items ci where ci.task_id = t.id)
+		from tasks t
+		where t.id = $1
+	`, taskID).Scan(&enabled, &status, &done, &total); err != nil {
+		return false, nil, err
+	}
+	if !enabled || total == 0 || done < total {
+		return false, nil, nil
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return false, nil, err
+	}
+	target := wf.doneStatus()
+	if target == "" || wf.isDone(status) {
+		return false, nil, nil
+	}
+
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer sp.Rollback(ctx)
+
+	if _, _, err := applyTaskChange(ctx, sp, uid, projectID, taskID, taskChange{Status: &target}); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			return false, apiErr.Body, nil
+		}
+		return false, nil, err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+func (h *Handler) ListChecklist(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+checklistColumns+`
+		from task_checklist_items ci
+		left join users cu on cu.id = ci.assignee_id
+		where ci.task_id = $1
+		order by ci.sort_index asc, ci.created_at asc
+	`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]ChecklistItem, 0)
+	done := 0
+	for rows.Next() {
+		var it ChecklistItem
+		if err := scanChecklistItem(rows, &it); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if it.Done {
+			done++
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items, "done": done, "total": len(items)})
+}
+
+// AddChecklistItem inserts at sort_index (shifting the rest down) or appends.
+func (h *Handler) AddChecklistItem(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req createChecklistItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	text, ok := normalizeChecklistText(req.Text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid text"})
+		return
+	}
+	if req.SortIndex != nil && *req.SortIndex < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_index"})
+		return
+	}
+
+	var assignee *uuid.UUID
+	if req.AssigneeID != nil && strings.TrimSpace(*req.AssigneeID) != "" {
+		a, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*req.AssigneeID)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee id"})
+			return
+		}
+		assignee = &a
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTaskForChecklist(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+	if assignee != nil {
+		if err := checkAssignee(ctx, tx, projectID, assignee.String()); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	var item ChecklistItem
+	if err := scanChecklistItem(tx.QueryRow(ctx, `
+		with desired as (
+			select least(
+				coalesce($4::int, 2147483647),
+				(select count(*) from task_checklist_items where task_id = $1)
+			)::int as idx
+		), shifted as (
+			update task_checklist_items
+			set sort_index = sort_index + 1
+			where task_id = $1
+				and sort_index >= (select idx from desired)
+		), inserted as (
+			insert into task_checklist_items (task_id, body, assignee_id, sort_index)
+			values ($1, $2, $3, (select idx from desired))
+			returning *
+		)
+		select `+checklistColumns+`
+		from inserted ci
+		left join users cu on cu.id = ci.assignee_id
+	`, taskID, text, assignee, req.SortIndex), &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// A new unchecked item never completes a task, so no auto-move here.
+	task, err := loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, checklistWriteResp{Item: &item, Task: task})
+}
+
+// UpdateChecklistItem takes a merge patch (text, done, assignee_id).
+func (h *Handler) UpdateChecklistItem(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	itemID, ok := parseUUIDParam(c, "itemId", "item")
+	if !ok {
+		return
+	}
+
+	var patch updateChecklistItemPatch
+	if err := decodeMergePatch(c.Request.Body, &patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	var text *string
+	if patch.Text.Set {
+		t, ok := normalizeChecklistText(patch.Text.Value)
+		if patch.Text.Null || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid text"})
+			return
+		}
+		text = &t
+	}
+
+	var done *bool
+	if patch.Done.Set {
+		if patch.Done.Null {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "done cannot be null"})
+			return
+		}
+		done = &patch.Done.Value
+	}
+
+	// null or "" unassigns
+	assigneeMode := "keep"
+	var assignee *uuid.UUID
+	if patch.AssigneeID.Set {
+		assigneeMode = "null"
+		if v := strings.TrimSpace(patch.AssigneeID.Value); !patch.AssigneeID.Null && v != "" {
+			a, err := uuid.Parse(strings.ToLower(v))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee id"})
+				return
+			}
+			assignee = &a
+			assigneeMode = "set"
+		}
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTaskForChecklist(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+	if assignee != nil {
+		if err := checkAssignee(ctx, tx, projectID, assignee.String()); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	var item ChecklistItem
+	err = scanChecklistItem(tx.QueryRow(ctx, `
+		with updated as (
+			update task_checklist_items
+			set
+				body = coalesce($3, body),
+				done = coalesce($4, done),
+				assignee_id = case
+					when $5 = 'keep' then assignee_id
+					when $5 = 'null' then null
+					else $6::uuid
+				end
+			where id = $1 and task_id = $2
+			returning *
+		)
+		select `+checklistColumns+`
+		from updated ci
+		left join users cu on cu.id = ci.assignee_id
+	`, itemID, taskID, text, done, assigneeMode, assignee), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	resp := checklistWriteResp{Item: &item}
+	if done != nil && *done {
+		resp.AutoMoved, resp.AutoMoveBlocked, err = autoCompleteTask(ctx, tx, uid, projectID, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	if resp.Task, err = loadTask(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteChecklistItem removes the item and closes the gap. Removing the last
+// unchecked item can complete the checklist, so it may auto-move the task.
+func (h *Handler) DeleteChecklistItem(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	itemID, ok := parseUUIDParam(c, "itemId", "item")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTaskForChecklist(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var deletedSort int
+	var wasDone bool
+	err = tx.QueryRow(ctx, `
+		delete from task_checklist_items
+		where id = $1 and task_id = $2
+		returning sort_index, done
+	`, itemID, taskID).Scan(&deletedSort, &wasDone)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update task_checklist_items
+		set sort_index = sort_index - 1
+		where task_id = $1 and sort_index > $2
+	`, taskID, deletedSort); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	var resp checklistWriteResp
+	if !wasDone {
+		resp.AutoMoved, resp.AutoMoveBlocked, err = autoCompleteTask(ctx, tx, uid, projectID, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	if resp.Task, err = loadTask(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReorderChecklist takes every item id of the task in the new order.
+func (h *Handler) ReorderChecklist(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req reorderChecklistReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.ItemIDs))
+	seen := make(map[uuid.UUID]bool, len(req.ItemIDs))
+	for _, raw := range req.ItemIDs {
+		id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(raw)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid item id"})
+			return
+		}
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate item id"})
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockTaskForChecklist(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// A partial list would leave the others with clashing positions.
+	var total, matched int
+	if err := tx.QueryRow(ctx, `
+		select count(*), count(*) filter (where id = any($2))
+		from task_checklist_items
+		where task_id = $1
+	`, taskID, ids).Scan(&total, &matched); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if matched != len(ids) || total != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "item_ids must list every item of the task"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		with ord(iid, ord) as (
+			select * from unnest($2::uuid[]) with ordinality
+		)
+		update task_checklist_items ci
+		set sort_index = (ord.ord - 1)
+		from ord
+		where ci.id = ord.iid
+			and ci.task_id = $1
+	`, taskID, ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Comment DTOs (responses) =========
+type Comment struct {
+	ID             string    `json:"id"`
+	TaskID         string    `json:"task_id"`
+	ParentID       *string   `json:"parent_id"`
+	AuthorID       *string   `json:"author_id"`
+	AuthorUsername *string   `json:"author_username"`
+	Body           string    `json:"body"`
+	CreatedAt      string    `json:"created_at"`
+	EditedAt       *string   `json:"edited_at"`
+	Deleted        bool      `json:"deleted"`
+	Replies        []Comment `json:"replies"`
+}
+
+// commentWriteResp is a saved comment plus any @usernames in it that aren't
+// project members.
+type commentWriteResp struct {
+	Comment
+	UnresolvedMentions []UnresolvedMention `json:"unresolved_mentions,omitempty"`
+}
+
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
+// ========= Requests =========
+type createCommentReq struct {
+	Body     string  `json:"body"`
+	ParentID *string `json:"parent_id"`
+}
+
+type updateCommentReq struct {
+	Body string `json:"body"`
+}
+
+const (
+	maxCommentBody      = 10000
+	defaultCommentLimit = 20
+	maxCommentLimit     = 100
+)
+
+const commentColumns = `
+	cm.id::text,
+	cm.task_id::text,
+	cm.parent_id::text,
+	cm.author_id::text,
+	au.username,
+	cm.body,
+	cm.created_at,
+	cm.edited_at,
+	cm.deleted_at is not null
+`
+
+func scanComment(row pgx.Row, cm *Comment) error {
+	var createdAt time.Time
+	var editedAt *time.Time
+	if err := row.Scan(
+		&cm.ID,
+		&cm.TaskID,
+		&cm.ParentID,
+		&cm.AuthorID,
+		&cm.AuthorUsername,
+		&cm.Body,
+		&createdAt,
+		&editedAt,
+		&cm.Deleted,
+	); err != nil {
+		return err
+	}
+	cm.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	if editedAt != nil {
+		s := editedAt.UTC().Format(time.RFC3339)
+		cm.EditedAt = &s
+	}
+	cm.Replies = []Comment{}
+	return nil
+}
+
+func normalizeCommentBody(s string) (string, bool) {
+	body := strings.TrimSpace(s)
+	return body, body != "" && len([]rune(body)) <= maxCommentBody
+}
+
+// queryInt reads a non-negative integer query param, falling back to def.
+func queryInt(c *gin.Context, name string, def int) (int, bool) {
+	raw := strings.TrimSpace(c.Query(name))
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// ListComments pages through a task's top-level comments, oldest first, each
+// with all of its replies.
+func (h *Handler) ListComments(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	limit, ok := queryInt(c, "limit", defaultCommentLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxCommentLimit {
+		limit = maxCommentLimit
+	}
+	offset, ok := queryInt(c, "offset", 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	page := CommentPage{Comments: []Comment{}, Limit: limit, Offset: offset}
+	if err := h.DB.QueryRow(ctx, `
+		select count(*) from task_comments where task_id = $1 and parent_id is null
+	`, taskID).Scan(&page.Total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		with top as (
+			select id, created_at
+			from task_comments
+			where task_id = $1 and parent_id is null
+			order by created_at asc, id asc
+			limit $2 offset $3
+		)
+		select `+commentColumns+`
+		from task_comments cm
+		left join users au on au.id = cm.author_id
+		where cm.id in (select id from top)
+			or cm.parent_id in (select id from top)
+		order by cm.created_at asc, cm.id asc
+	`, taskID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	// Top-level rows always sort before their replies (a reply is newer than
+	// its parent), so parents are indexed before any reply needs them.
+	index := make(map[string]int)
+	for rows.Next() {
+		var cm Comment
+		if err := scanComment(rows, &cm); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if cm.ParentID == nil {
+			index[cm.ID] = len(page.Comments)
+			page.Comments = append(page.Comments, cm)
+			continue
+		}
+		if i, ok := index[*cm.ParentID]; ok {
+			page.Comments[i].Replies = append(page.Comments[i].Replies, cm)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) CreateComment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req createCommentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	body, ok := normalizeCommentBody(req.Body)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	var parentID *uuid.UUID
+	if req.ParentID != nil && strings.TrimSpace(*req.ParentID) != "" {
+		p, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*req.ParentID)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent id"})
+			return
+		}
+		parentID = &p
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if parentID != nil {
+		// Replies only hang off top-level comments on the same task.
+		var topLevel bool
+		err := tx.QueryRow(ctx, `
+			select parent_id is null
+			from task_comments
+			where id = $1 and task_id = $2
+		`, *parentID, taskID).Scan(&topLevel)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "parent comment not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if !topLevel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replies can't be nested"})
+			return
+		}
+	}
+
+	var out Comment
+	if err := scanComment(tx.QueryRow(ctx, `
+		with inserted as (
+			insert into task_comments (task_id, author_id, parent_id, body)
+			values ($1, $2::uuid, $3, $4)
+			returning *
+		)
+		select `+commentColumns+`
+		from inserted cm
+		left join users au on au.id = cm.author_id
+	`, taskID, uid, parentID, body), &out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	commentID, err := uuid.Parse(out.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Mentions first, so a mentioned watcher isn't notified twice.
+	unresolved, err := syncMentions(ctx, tx, uid, projectID, taskID, &commentID, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if err := notifyComment(ctx, tx, uid, projectID, taskID, commentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if err := watchTask(ctx, tx, taskID, uid, watchCommented); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, commentWriteResp{Comment: out, UnresolvedMentions: unresolved})
+}
+
+func (h *Handler) UpdateComment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUUIDParam(c, "commentId", "comment")
+	if !ok {
+		return
+	}
+
+	var req updateCommentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	body, ok := normalizeCommentBody(req.Body)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Only the author edits, and deleted comments stay deleted.
+	var out Comment
+	err = scanComment(tx.QueryRow(ctx, `
+		with updated as (
+			update task_comments
+			set body = $4, edited_at = now()
+			where id = $1
+				and task_id = $2
+				and author_id = $3::uuid
+				and deleted_at is null
+			returning *
+		)
+		select `+commentColumns+`
+		from updated cm
+		left join users au on au.id = cm.author_id
+	`, commentID, taskID, uid, body), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Only people newly mentioned by the edit are notified.
+	unresolved, err := syncMentions(ctx, tx, uid, projectID, taskID, &commentID, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, commentWriteResp{Comment: out, UnresolvedMentions: unresolved})
+}
+
+// DeleteComment removes the author's comment. A top-level comment that still
+// has replies is blanked instead so the thread stays readable.
+func (h *Handler) DeleteComment(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUUIDParam(c, "commentId", "comment")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var hasReplies bool
+	err = tx.QueryRow(ctx, `
+		select exists (select 1 from task_comments r where r.parent_id = cm.id)
+		from task_comments cm
+		where cm.id = $1
+			and cm.task_id = $2
+			and cm.author_id = $3::uuid
+			and cm.deleted_at is null
+		for update
+	`, commentID, taskID, uid).Scan(&hasReplies)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if hasReplies {
+		_, err = tx.Exec(ctx, `
+			update task_comments
+			set body = '', deleted_at = now()
+			where id = $1
+		`, commentID)
+		if err == nil {
+			_, err = tx.Exec(ctx, `delete from task_mentions where comment_id = $1`, commentID)
+		}
+	} else {
+		// Removing the last reply under a blanked comment takes the
+		// placeholder with it.
+		_, err = tx.Exec(ctx, `
+			with removed as (
+				delete from task_comments where id = $1
+				returning parent_id
+			)
+			delete from task_comments p
+			using removed r
+			where p.id = r.parent_id
+				and p.deleted_at is not null
+				and not exists (
+					select 1 from task_comments o
+					where o.parent_id = p.id and o.id <> $1
+				)
+		`, commentID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Dependency DTOs (responses) =========
+
+// TaskRef is the short form of a task shown on the other end of a link.
+type TaskRef struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Done   bool   `json:"done"`
+}
+
+type TaskDependencies struct {
+	BlockedBy []TaskRef `json:"blocked_by"`
+	Blocks    []TaskRef `json:"blocks"`
+}
+
+// dependencyWriteResp is returned when a link is added; the task may have
+// been moved into the project's auto-block status.
+type dependencyWriteResp struct {
+	TaskDependencies
+	Task            Task  `json:"task"`
+	AutoMoved       bool  `json:"auto_moved"`
+	AutoMoveBlocked gin.H `json:"auto_move_blocked,omitempty"`
+}
+
+// ========= Requests =========
+type addTaskDependencyReq struct {
+	BlockerID string `json:"blocker_id"`
+}
+
+type updateAutoBlockReq struct {
+	// Status is where tasks go when an open blocker is added; null or ""
+	// turns auto-blocking off.
+	Status *string `json:"status"`
+}
+
+func loadTaskDependencies(ctx context.Context, q dbtx, taskID uuid.UUID) (TaskDependencies, error) {
+	out := TaskDependencies{BlockedBy: []TaskRef{}, Blocks: []TaskRef{}}
+
+	rows, err := q.Query(ctx, `
+		select 'blocked_by', t.id::text, t.title, t.status, t.completed_at is not null
+		from task_dependencies d
+		join tasks t on t.id = d.blocker_id
+		where d.blocked_id = $1 and t.deleted_at is null
+		union all
+		select 'blocks', t.id::text, t.title, t.status, t.completed_at is not null
+		from task_dependencies d
+		join tasks t on t.id = d.blocked_id
+		where d.blocker_id = $1 and t.deleted_at is null
+		order by 1, 3
+	`, taskID)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var side string
+		var r TaskRef
+		if err := rows.Scan(&side, &r.ID, &r.Title, &r.Status, &r.Done); err != nil {
+			return out, err
+		}
+		if side == "blocks" {
+			out.Blocks = append(out.Blocks, r)
+		} else {
+			out.BlockedBy = append(out.BlockedBy, r)
+		}
+	}
+	return out, rows.Err()
+}
+
+// checkOpenBlockers returns a 409 apiError listing the task's unfinished
+// blockers, if it has any.
+func checkOpenBlockers(ctx context.Context, q dbtx, taskID uuid.UUID) error {
+	rows, err := q.Query(ctx, `
+		select t.id::text, t.title, t.status
+		from task_dependencies d
+		join tasks t on t.id = d.blocker_id
+		where d.blocked_id = $1 and t.completed_at is null and t.deleted_at is null
+		order by t.title
+	`, taskID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	open := make([]TaskRef, 0)
+	for rows.Next() {
+		var r TaskRef
+		if err := rows.Scan(&r.ID, &r.Title, &r.Status); err != nil {
+			return err
+		}
+		open = append(open, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return &apiError{Status: http.StatusConflict, Body: gin.H{
+			"error":    "task has open blockers",
+			"blockers": open,
+		}}
+	}
+	return nil
+}
+
+// blockedTaskIDs lists the tasks the given task blocks.
+func blockedTaskIDs(ctx context.Context, q dbtx, blockerID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.Query(ctx, `
+		select blocked_id from task_dependencies where blocker_id = $1
+	`, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// lockDependencyGraph serializes dependency inserts in a project until tx
+// ends. Each insert's cycle check must see the links committed before it:
+// without the lock, two inserts with disjoint endpoints (A->B and C->D
+// beside B->C and D->A) would each pass against their own snapshot and
+// together close a loop.
+func lockDependencyGraph(ctx context.Context, tx pgx.Tx, projectID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1))`, "deps:"+projectID.String())
+	return err
+}
+
+// releaseBlocked runs releaseTasks for everything blockerID blocks. Call it
+// once the blocker has been finished.
+func releaseBlocked(ctx context.Context, tx pgx.Tx, uid string, projectID, blockerID uuid.UUID) error {
+	ids, err := blockedTaskIDs(ctx, tx, blockerID)
+	if err != nil {
+		return err
+	}
+	return releaseTasks(ctx, tx, uid, projectID, ids)
+}
+
+// releaseTasks moves auto-blocked tasks that no longer have an open blocker
+// back to the status they were blocked from. Tasks someone blocked by hand,
+// or moved since, are left alone. A release refused by the project's rules
+// is skipped; the task simply stays blocked.
+func releaseTasks(ctx context.Context, tx pgx.Tx, uid string, projectID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		var status string
+		var blockedFrom, autoStatus *string
+		var stillBlocked bool
+		err := tx.QueryRow(ctx, `
+			select t.status, t.blocked_from, p.auto_block_status,
+				exists (
+					select 1
+					from task_dependencies d
+					join tasks bt on bt.id = d.blocker_id
+					where d.blocked_id = t.id and bt.completed_at is null
+						and bt.deleted_at is null
+				)
+			from tasks t
+			join projects p on p.id = t.project_id
+			where t.id = $1 and t.project_id = $2 and t.deleted_at is null
+		`, id, projectID).Scan(&status, &blockedFrom, &autoStatus, &stillBlocked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		if blockedFrom == nil || autoStatus == nil || status != *autoStatus || stillBlocked {
+			continue
+		}
+
+		target := *blockedFrom
+		if _, ok := wf.find(target); !ok {
+			target = wf.defaultStatus()
+		}
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		_, _, err = applyTaskChange(ctx, sp, uid, projectID, id, taskChange{Status: &target})
+		if err != nil {
+			sp.Rollback(ctx)
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				continue
+			}
+			return err
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// autoBlockTask parks the task in the project's auto-block status after an
+// open blocker was added, remembering where it came from. Like the checklist
+// auto-move it runs in a savepoint and reports a refusal instead of failing.
+func autoBlockTask(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID, blockerTitle string) (bool, gin.H, error) {
+	var status string
+	var autoStatus *string
+	var done bool
+	if err := tx.QueryRow(ctx, `
+		select t.status, p.auto_block_status, t.completed_at is not null
+		from tasks t
+		join projects p on p.id = t.project_id
+		where t.id = $1
+	`, taskID).Scan(&status, &autoStatus, &done); err != nil {
+		return false, nil, err
+	}
+	if autoStatus == nil || done || status == *autoStatus {
+		return false, nil, nil
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return false, nil, err
+	}
+	if _, ok := wf.find(*autoStatus); !ok {
+		return false, nil, nil
+	}
+
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer sp.Rollback(ctx)
+
+	reason := "Blocked by " + blockerTitle
+	_, _, err = applyTaskChange(ctx, sp, uid, projectID, taskID, taskChange{
+		Status:    autoStatus,
+		ReasonSet: true,
+		Reason:    &reason,
+	})
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			return false, apiErr.Body, nil
+		}
+		return false, nil, err
+	}
+	if _, err := sp.Exec(ctx, `update tasks set blocked_from = $2 where id = $1`, taskID, status); err != nil {
+		return false, nil, err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+func (h *Handler) GetTaskDependencies(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	deps, err := loadTaskDependencies(ctx, h.DB, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, deps)
+}
+
+// AddTaskDependency records that the task is blocked by blocker_id.
+func (h *Handler) AddTaskDependency(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req addTaskDependencyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	blockerID, err := uuid.Parse(strings.ToLower(strings.TrimSpace(req.BlockerID)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid blocker_id"})
+		return
+	}
+	if blockerID == taskID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task cannot block itself"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// The cycle check below reads the whole graph, so inserts into it run
+	// one at a time per project.
+	if err := lockDependencyGraph(ctx, tx, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Both tasks must be in this project; lock them so neither is deleted
+	// or moved away before the link is written.
+	var found int
+	if err := tx.QueryRow(ctx, `
+		select count(*) from (
+			select id from tasks
+			where project_id = $1 and id in ($2, $3) and deleted_at is null
+			order by id
+			for update
+		) t
+	`, projectID, taskID, blockerID).Scan(&found); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if found != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	// blocker -> task closes a cycle if the blocker already (transitively)
+	// waits on the task.
+	var cycle bool
+	if err := tx.QueryRow(ctx, `
+		with recursive reach(id) as (
+			select blocker_id from task_dependencies where blocked_id = $2
+			union
+			select d.blocker_id
+			from task_dependencies d
+			join reach r on d.blocked_id = r.id
+		)
+		select exists (select 1 from reach where id = $1)
+	`, taskID, blockerID).Scan(&cycle); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cycle {
+		c.JSON(http.StatusConflict, gin.H{"error": "dependency would create a cycle"})
+		return
+	}
+
+	var blockerTitle string
+	var blockerOpen, inserted bool
+	if err := tx.QueryRow(ctx, `
+		with ins as (
+			insert into task_dependencies (blocker_id, blocked_id)
+			values ($1, $2)
+			on conflict do nothing
+			returning 1
+		)
+		select t.title, t.completed_at is null, exists (select 1 from ins)
+		from tasks t
+		where t.id = $1
+	`, blockerID, taskID).Scan(&blockerTitle, &blockerOpen, &inserted); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	var resp dependencyWriteResp
+	if inserted && blockerOpen {
+		resp.AutoMoved, resp.AutoMoveBlocked, err = autoBlockTask(ctx, tx, uid, projectID, taskID, blockerTitle)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	if resp.TaskDependencies, err = loadTaskDependencies(ctx, tx, taskID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if resp.Task, err = loadTask(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteTaskDependency removes "task is blocked by blockerId". If that was
+// the task's last open blocker an auto-blocked task is released.
+func (h *Handler) DeleteTaskDependency(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	blockerID, ok := parseUUIDParam(c, "blockerId", "blocker")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Links to or from a task in the trash stay as they are until it is
+	// restored or purged.
+	cmd, err := tx.Exec(ctx, `
+		delete from task_dependencies d
+		using tasks t, tasks bt
+		where t.id = d.blocked_id
+			and bt.id = d.blocker_id
+			and t.project_id = $1
+			and t.deleted_at is null
+			and bt.deleted_at is null
+			and d.blocked_id = $2
+			and d.blocker_id = $3
+	`, projectID, taskID, blockerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dependency not found"})
+		return
+	}
+
+	if err := releaseTasks(ctx, tx, uid, projectID, []uuid.UUID{taskID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// UpdateAutoBlock sets (or clears) the status tasks are moved to when an
+// open blocker is added. Only the owner changes project settings.
+func (h *Handler) UpdateAutoBlock(c *gin.Context) {
+	ownerID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	var req updateAutoBlockReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	var status *string
+	if req.Status != nil {
+		if s := strings.TrimSpace(*req.Status); s != "" {
+			status = &s
+		}
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Same lock as UpdateWorkflow, so the status can't be removed between
+	// the check and the write.
+	var locked string
+	if err := tx.QueryRow(ctx, `
+		select id::text from projects
+		where id = $1 and owner_id = $2::uuid
+		for update
+	`, projectID, ownerID).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if status != nil {
+		wf, err := loadWorkflow(ctx, tx, projectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if _, ok := wf.find(*status); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		if wf.isDone(*status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "auto-block status cannot be a done status"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update projects set auto_block_status = $2 where id = $1
+	`, projectID, status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auto_block_status": status})
+}
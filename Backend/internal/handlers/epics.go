@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ========= Epic DTOs (responses) =========
+type Epic struct {
+	ID          string   `json:"id"`
+	ProjectID   string   `json:"project_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	StartDate   string   `json:"start_date"`
+	EndDate     string   `json:"end_date"`
+	DependsOn   []string `json:"depends_on"`
+	SortIndex   int      `json:"sort_index"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// ========= Requests =========
+type createEpicReq struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+}
+
+type updateEpicReq struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	StartDate   *string `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+	SortIndex   *int    `json:"sort_index"`
+}
+
+type addEpicDependencyReq struct {
+	DependsOnID string `json:"depends_on_id"`
+}
+
+// epicInProject rejects epic ids that belong to another project, so a task
+// can never be grouped under a different project's roadmap.
+func epicInProject(ctx context.Context, q dbtx, projectID, epicID uuid.UUID) error {
+	var ok bool
+	if err := q.QueryRow(ctx, `
+		select exists (
+			select 1 from epics where id = $1 and project_id = $2
+		)
+	`, epicID, projectID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return newAPIError(http.StatusBadRequest, "invalid epic id")
+	}
+	return nil
+}
+
+const epicColumns = `
+	e.id::text,
+	e.project_id::text,
+	e.title,
+	e.description,
+	e.start_date,
+	e.end_date,
+	e.sort_index,
+	e.created_at,
+	coalesce((
+		select array_agg(d.depends_on_id::text order by d.created_at)
+		from epic_dependencies d
+		where d.epic_id = e.id
+	), '{}')
+`
+
+func scanEpic(row pgx.Row, e *Epic) error {
+	var start, end, createdAt time.Time
+	if err := row.Scan(
+		&e.ID,
+		&e.ProjectID,
+		&e.Title,
+		&e.Description,
+		&start,
+		&end,
+		&e.SortIndex,
+		&createdAt,
+		&e.DependsOn,
+	); err != nil {
+		return err
+	}
+	e.StartDate = start.Format(time.DateOnly)
+	e.EndDate = end.Format(time.DateOnly)
+	e.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	return time.Parse(time.DateOnly, strings.TrimSpace(s))
+}
+
+func (h *Handler) ListEpics(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+epicColumns+`
+		from epics e
+		where e.project_id = $1
+		order by e.start_date asc, e.sort_index asc, e.created_at asc
+	`, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	out := make([]Epic, 0)
+	for rows.Next() {
+		var e Epic
+		if err := scanEpic(rows, &e); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateEpic(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	var req createEpicReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing title"})
+		return
+	}
+
+	start, err := parseDate(req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+		return
+	}
+	end, err := parseDate(req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date before start_date"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var out Epic
+	if err := scanEpic(h.DB.QueryRow(ctx, `
+		with inserted as (
+			insert into epics (project_id, title, description, start_date, end_date, sort_index)
+			values (
+				$1, $2, $3, $4, $5,
+				coalesce((select max(sort_index) + 1 from epics where project_id = $1), 0)
+			)
+			returning *
+		)
+		select `+epicColumns+`
+		from inserted e
+	`, projectID, title, strings.TrimSpace(req.Description), start, end), &out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) UpdateEpic(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	epicID, ok := parseUUIDParam(c, "epicId", "epic")
+	if !ok {
+		return
+	}
+
+	var req updateEpicReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	var title *string
+	if req.Title != nil {
+		t := strings.TrimSpace(*req.Title)
+		if t == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing title"})
+			return
+		}
+		title = &t
+	}
+
+	var description *string
+	if req.Description != nil {
+		d := strings.TrimSpace(*req.Description)
+		description = &d
+	}
+
+	var start, end *time.Time
+	if req.StartDate != nil {
+		d, err := parseDate(*req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+			return
+		}
+		start = &d
+	}
+	if req.EndDate != nil {
+		d, err := parseDate(*req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date"})
+			return
+		}
+		end = &d
+	}
+
+	if req.SortIndex != nil && *req.SortIndex < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_index"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var out Epic
+	err := scanEpic(h.DB.QueryRow(ctx, `
+		with updated as (
+			update epics
+			set
+				title = coalesce($3, title),
+				description = coalesce($4, description),
+				start_date = coalesce($5, start_date),
+				end_date = coalesce($6, end_date),
+				sort_index = coalesce($7, sort_index)
+			where project_id = $1 and id = $2
+			returning *
+		)
+		select `+epicColumns+`
+		from updated e
+	`, projectID, epicID, title, description, start, end, req.SortIndex), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "epic not found"})
+			return
+		}
+		// end_date >= start_date check
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date before start_date"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) DeleteEpic(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	epicID, ok := parseUUIDParam(c, "epicId", "epic")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	// Tasks in the epic stay on the board; tasks.epic_id is "on delete set null".
+	cmd, err := h.DB.Exec(ctx, `
+		delete from epics
+		where project_id = $1 and id = $2
+	`, projectID, epicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "epic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *Handler) AddEpicDependency(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	epicID, ok := parseUUIDParam(c, "epicId", "epic")
+	if !ok {
+		return
+	}
+
+	var req addEpicDependencyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	dependsOnID, err := uuid.Parse(strings.ToLower(strings.TrimSpace(req.DependsOnID)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid depends_on_id"})
+		return
+	}
+	if dependsOnID == epicID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "epic cannot depend on itself"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// The cycle check reads every epic link in the project, so inserts run
+	// one at a time per project and each sees the links committed before it.
+	if err := lockEpicGraph(ctx, tx, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Both epics must be in this project; lock them (in id order, so two
+	// inserts on the same pair can't deadlock) until the link is written.
+	var found int
+	if err := tx.QueryRow(ctx, `
+		select count(*) from (
+			select id from epics
+			where project_id = $1 and id in ($2, $3)
+			order by id
+			for update
+		) e
+	`, projectID, epicID, dependsOnID).Scan(&found); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if found != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "epic not found"})
+		return
+	}
+
+	// Adding epic -> dependsOn closes a cycle if epic is already reachable
+	// from dependsOn.
+	var cycle bool
+	if err := tx.QueryRow(ctx, `
+		with recursive reach(id) as (
+			select depends_on_id from epic_dependencies where epic_id = $2
+			union
+			select d.depends_on_id
+			from epic_dependencies d
+			join reach r on d.epic_id = r.id
+		)
+		select exists (select 1 from reach where id = $1)
+	`, epicID, dependsOnID).Scan(&cycle); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cycle {
+		c.JSON(http.StatusConflict, gin.H{"error": "dependency would create a cycle"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		insert into epic_dependencies (epic_id, depends_on_id)
+		values ($1, $2)
+		on conflict do nothing
+	`, epicID, dependsOnID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	var out Epic
+	if err := scanEpic(tx.QueryRow(ctx, `
+		select `+epicColumns+`
+		from epics e
+		where e.id = $1
+	`, epicID), &out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// lockEpicGraph holds a project's epic dependency graph until tx ends.
+func lockEpicGraph(ctx context.Context, tx pgx.Tx, projectID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1))`, "epic-deps:"+projectID.String())
+	return err
+}
+
+func (h *Handler) DeleteEpicDependency(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	epicID, ok := parseUUIDParam(c, "epicId", "epic")
+	if !ok {
+		return
+	}
+	dependsOnID, ok := parseUUIDParam(c, "dependsOnId", "dependency")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	cmd, err := h.DB.Exec(ctx, `
+		delete from epic_dependencies d
+		using epics e
+		where e.id = d.epic_id
+			and e.project_id = $1
+			and d.epic_id = $2
+			and d.depends_on_id = $3
+	`, projectID, epicID, dependsOnID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dependency not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
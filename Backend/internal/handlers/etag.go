@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tasks and projects carry a version that goes up on every edit. It is sent
+// as the ETag, and edits may send it back in If-Match so two people editing
+// the same thing can't silently overwrite each other.
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatch reports whether the request may write a resource at version. A
+// missing If-Match or "*" allows any version; otherwise one of the listed
+// tags must match. Weak tags (W/"3") compare by their value.
+func ifMatch(c *gin.Context, version int) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	want := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// lockTaskVersion locks the task row for the rest of tx and returns its
+// version.
+func lockTaskVersion(ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID) (int, error) {
+	var v int
+	err := tx.QueryRow(ctx, `
+		select version from tasks
+		where project_id = $1 and id = $2 and deleted_at is null
+		for update
+	`, projectID, taskID).Scan(&v)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, newAPIError(http.StatusNotFound, "task not found")
+	}
+	return v, err
+}
+
+// checkTaskIfMatch answers 412 with the current task, and returns false, when
+// the request's If-Match doesn't name the task's current version. It locks
+// the task so the version can't move before tx commits.
+func checkTaskIfMatch(c *gin.Context, ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID) bool {
+	version, err := lockTaskVersion(ctx, tx, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return false
+	}
+	if ifMatch(c, version) {
+		return true
+	}
+
+	current, err := loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return false
+	}
+	setETag(c, current.Version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "task was changed by someone else", "current": current})
+	return false
+}
@@ -2,21 +2,111 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"forge-api/internal/storage"
 )
 
 type Handler struct {
 	DB        *pgxpool.Pool
 	JWTSecret []byte
+
+	// Blobs holds attachment contents; nil disables attachment uploads.
+	Blobs              storage.BlobStore
+	MaxAttachmentBytes int64
+
+	// TrashRetention is how long deleted tasks can be restored before they
+	// are purged.
+	TrashRetention time.Duration
 }
 
 func New(db *pgxpool.Pool, jwtSecret []byte) *Handler {
 	return &Handler{DB: db, JWTSecret: jwtSecret}
 }
 
+// dbtx is satisfied by both the pool and a transaction, so helpers can run
+// inside whichever one the handler is using.
+type dbtx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func contextTimeout(c *gin.Context, d time.Duration) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(c.Request.Context(), d)
-}
\ No newline at end of file
+}
+
+// parseUUIDParam reads a path param as a UUID, writing a 400 when it is
+// missing or malformed. label is used in the error ("invalid <label> id").
+func parseUUIDParam(c *gin.Context, name, label string) (uuid.UUID, bool) {
+	raw := strings.TrimSpace(c.Param(name))
+	if raw == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing " + label + " id"})
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(strings.ToLower(raw))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + label + " id"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// requireProjectMember applies the same rule as the task handlers: the caller
+// must have a projects_members row for the project. It writes the error
+// response itself and returns false when access is denied.
+func requireProjectMember(c *gin.Context, ctx context.Context, q dbtx, projectID uuid.UUID, uid string) bool {
+	var allowed bool
+	if err := q.QueryRow(ctx, `
+		select exists (
+			select 1
+			from projects_members
+			where project_id = $1
+			and user_id::text = $2
+		)
+	`, projectID, uid).Scan(&allowed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a project member"})
+		return false
+	}
+	return true
+}
+
+// apiError is a client-facing failure raised below the handler, e.g. from a
+// helper shared by several endpoints. writeError maps it onto the response.
+type apiError struct {
+	Status int
+	Body   gin.H
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d: %v", e.Status, e.Body["error"])
+}
+
+func newAPIError(status int, msg string) *apiError {
+	return &apiError{Status: status, Body: gin.H{"error": msg}}
+}
+
+// writeError answers with the apiError's status and body, or a plain 500 for
+// anything else.
+func writeError(c *gin.Context, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		c.JSON(apiErr.Status, apiErr.Body)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= History DTOs (responses) =========
+type TaskEvent struct {
+	ID            string                 `json:"id"`
+	TaskID        string                 `json:"task_id"`
+	Kind          string                 `json:"kind"` // created | updated | moved | deleted
+	ActorID       *string                `json:"actor_id"`
+	ActorUsername *string                `json:"actor_username"`
+	Changes       map[string]FieldChange `json:"changes"`
+	CreatedAt     string                 `json:"created_at"`
+}
+
+// FieldChange is one field's value before and after an event. A created
+// or restored event has no From values and a deleted event no To values.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+const (
+	taskEventCreated  = "created"
+	taskEventUpdated  = "updated"
+	taskEventMoved    = "moved"
+	taskEventDeleted  = "deleted"
+	taskEventRestored = "restored"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// taskFields is what history tracks about a task; derived values such as
+// counts, keys and sort_index are left out. rank stands for the position: it
+// only changes when the task itself is moved.
+func taskFields(t Task) map[string]any {
+	str := func(p *string) any {
+		if p == nil {
+			return nil
+		}
+		return *p
+	}
+	var estimate any
+	if t.EstimateHours != nil {
+		estimate = *t.EstimateHours
+	}
+	return map[string]any{
+		"title":               t.Title,
+		"details":             t.Details,
+		"status":              t.Status,
+		"status_reason":       str(t.StatusReason),
+		"rank":                t.Rank,
+		"assignee_id":         str(t.AssigneeID),
+		"difficulty":          t.Difficulty,
+		"epic_id":             str(t.EpicID),
+		"start_date":          str(t.StartDate),
+		"due_at":              str(t.DueAt),
+		"estimate_hours":      estimate,
+		"checklist_auto_done": t.ChecklistAutoDone,
+		"required_skills":     t.RequiredSkills,
+		"role_key":            str(t.RoleKey),
+		"series_id":           str(t.SeriesID),
+		"project_id":          t.ProjectID,
+	}
+}
+
+// taskDiff lists the tracked fields that differ between two versions of a
+// task. A nil side stands for "no task" (before a create, after a delete).
+func taskDiff(before, after *Task) map[string]FieldChange {
+	var from, to map[string]any
+	if before != nil {
+		from = taskFields(*before)
+	}
+	if after != nil {
+		to = taskFields(*after)
+	}
+
+	out := make(map[string]FieldChange)
+	for _, m := range []map[string]any{from, to} {
+		for k := range m {
+			if _, done := out[k]; done || reflect.DeepEqual(from[k], to[k]) {
+				continue
+			}
+			out[k] = FieldChange{From: from[k], To: to[k]}
+		}
+	}
+	return out
+}
+
+// eventKind calls an update that only repositioned the task a move.
+func eventKind(diff map[string]FieldChange) string {
+	for k := range diff {
+		if k != "status" && k != "rank" && k != "status_reason" && k != "project_id" && k != "key" {
+			return taskEventUpdated
+		}
+	}
+	return taskEventMoved
+}
+
+// recordTaskEvent appends to task_events. Call it in the transaction that
+// made the change so history can't disagree with the task. actor may be ""
+// for changes made by the server itself.
+func recordTaskEvent(ctx context.Context, q dbtx, projectID, taskID uuid.UUID, actor, kind string, changes map[string]FieldChange) error {
+	// The task's watchers, other than whoever made the change, are notified.
+	_, err := q.Exec(ctx, `
+		with event as (
+			insert into task_events (project_id, task_id, actor_id, kind, changes)
+			values ($1, $2, nullif($3, '')::uuid, $4, $5)
+			returning id
+		)
+		insert into notifications (user_id, project_id, task_id, kind, event_id, actor_id)
+		select w.user_id, $1, $2, 'task_event', e.id, nullif($3, '')::uuid
+		from event e
+		join task_watchers w on w.task_id = $2
+		where w.user_id::text <> $3
+	`, projectID, taskID, actor, kind, changes)
+	return err
+}
+
+func scanTaskEvent(row pgx.Row, e *TaskEvent) error {
+	var changes []byte
+	var createdAt time.Time
+	if err := row.Scan(
+		&e.ID,
+		&e.TaskID,
+		&e.Kind,
+		&e.ActorID,
+		&e.ActorUsername,
+		&changes,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+	e.Changes = map[string]FieldChange{}
+	if err := json.Unmarshal(changes, &e.Changes); err != nil {
+		return err
+	}
+	e.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// GetTaskHistory lists a task's events oldest first. It still works after
+// the task has been deleted.
+func (h *Handler) GetTaskHistory(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	limit, ok := queryInt(c, "limit", defaultHistoryLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	offset, ok := queryInt(c, "offset", 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	// field=status narrows the list to events that touched that field, which
+	// is all cycle-time reporting needs.
+	field := strings.TrimSpace(c.Query("field"))
+	if field != "" {
+		if _, ok := taskFields(Task{})[field]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid field"})
+			return
+		}
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var total, all int
+	if err := h.DB.QueryRow(ctx, `
+		select count(*) filter (where $3 = '' or changes ? $3), count(*)
+		from task_events
+		where project_id = $1 and task_id = $2
+	`, projectID, taskID, field).Scan(&total, &all); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if all == 0 {
+		if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select
+			e.id::text,
+			e.task_id::text,
+			e.kind,
+			e.actor_id::text,
+			u.username,
+			e.changes,
+			e.created_at
+		from task_events e
+		left join users u on u.id = e.actor_id
+		where e.project_id = $1 and e.task_id = $2
+			and ($5 = '' or e.changes ? $5)
+		order by e.created_at asc, e.seq asc
+		limit $3 offset $4
+	`, projectID, taskID, limit, offset, field)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	events := make([]TaskEvent, 0)
+	for rows.Next() {
+		var e TaskEvent
+		if err := scanTaskEvent(rows, &e); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": total, "limit": limit, "offset": offset})
+}
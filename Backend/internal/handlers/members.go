@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// parseAssignee validates a client-sent assignee id. Blank means unassigned
+// and comes back as nil.
+func parseAssignee(raw *string) (*string, error) {
+	if raw == nil || strings.TrimSpace(*raw) == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*raw)))
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "invalid assignee id")
+	}
+	s := id.String()
+	return &s, nil
+}
+
+// checkAssignee refuses, with 422, to hand work in a project to someone who
+// isn't a member of it. The foreign key alone only proves the user exists.
+func checkAssignee(ctx context.Context, q dbtx, projectID uuid.UUID, userID string) error {
+	var member bool
+	if err := q.QueryRow(ctx, `
+		select exists (
+			select 1 from projects_members
+			where project_id = $1 and user_id = $2::uuid
+		)
+	`, projectID, userID).Scan(&member); err != nil {
+		return err
+	}
+	if !member {
+		return &apiError{Status: http.StatusUnprocessableEntity, Body: gin.H{
+			"error":       "invalid assignee",
+			"reason":      "assignee is not a member of this project",
+			"assignee_id": userID,
+		}}
+	}
+	return nil
+}
+
+// unassignMember clears userID from every task, checklist item and
+// recurring series in the project, recording each task's change, and stops
+// them watching its tasks. Call it when they stop being a member.
+func unassignMember(ctx context.Context, tx pgx.Tx, actor string, projectID, userID uuid.UUID) (int, error) {
+	if _, err := tx.Exec(ctx, `
+		delete from task_watchers w
+		using tasks t
+		where t.id = w.task_id and t.project_id = $1 and w.user_id = $2
+	`, projectID, userID); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := tx.QueryRow(ctx, `
+		with unassigned as (
+			update tasks
+			set assignee_id = null,
+				version = version + 1
+			where project_id = $1 and assignee_id = $2
+			returning id
+		), events as (
+			insert into task_events (project_id, task_id, actor_id, kind, changes)
+			select $1, id, nullif($3, '')::uuid, 'updated',
+				jsonb_build_object('assignee_id', jsonb_build_object('from', $2::text, 'to', null))
+			from unassigned
+			returning id, task_id
+		), notified as (
+			insert into notifications (user_id, project_id, task_id, kind, event_id, actor_id)
+			select w.user_id, $1, e.task_id, 'task_event', e.id, nullif($3, '')::uuid
+			from events e
+			join task_watchers w on w.task_id = e.task_id
+			where w.user_id::text <> $3
+		)
+		select count(*) from unassigned
+	`, projectID, userID, actor).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update task_checklist_items ci
+		set assignee_id = null
+		from tasks t
+		where t.id = ci.task_id and t.project_id = $1 and ci.assignee_id = $2
+	`, projectID, userID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update task_series
+		set assignee_id = null
+		where project_id = $1 and assignee_id = $2
+	`, projectID, userID); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// RemoveProjectMember takes a member off a project: the owner may remove
+// anyone else, and members may remove themselves (leave). Their tasks in the
+// project are unassigned.
+func (h *Handler) RemoveProjectMember(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	userID, ok := parseUUIDParam(c, "userId", "user")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var ownerID string
+	if err := tx.QueryRow(ctx, `
+		select p.owner_id::text
+		from projects p
+		join projects_members pm on pm.project_id = p.id and pm.user_id = $2::uuid
+		where p.id = $1
+	`, projectID, uid).Scan(&ownerID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a project member"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if userID.String() == ownerID {
+		c.JSON(http.StatusConflict, gin.H{"error": "the owner can't leave the project; delete it instead"})
+		return
+	}
+	if uid != ownerID && userID.String() != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can remove other members"})
+		return
+	}
+
+	cmd, err := tx.Exec(ctx, `
+		delete from projects_members
+		where project_id = $1 and user_id = $2
+	`, projectID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+
+	unassigned, err := unassignMember(ctx, tx, uid, projectID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "unassigned_tasks": unassigned})
+}
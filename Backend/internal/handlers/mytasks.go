@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ========= My tasks DTOs (responses) =========
+
+// MyTask is a task seen from the caller's cross-project lists.
+type MyTask struct {
+	Task
+	ProjectName string `json:"project_name"`
+}
+
+// MyTaskGroup heads a run of tasks in a grouped list. Key is the status key
+// or project id the tasks share.
+type MyTaskGroup struct {
+	Key   string `json:"key"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type MyTasksPage struct {
+	Tasks  []MyTask      `json:"tasks"`
+	Groups []MyTaskGroup `json:"groups,omitempty"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	// NextCursor fetches the following page; null on the last one.
+	NextCursor *string `json:"next_cursor"`
+}
+
+const (
+	defaultMyTasksLimit = 50
+	maxMyTasksLimit     = 200
+)
+
+// weekBounds returns the Monday-to-Monday week containing now in loc.
+func weekBounds(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	offset := (int(local.Weekday()) + 6) % 7 // days since Monday
+	start := time.Date(local.Year(), local.Month(), local.Day()-offset, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 7)
+}
+
+// GetMyDueTasks lists tasks assigned to the caller that have a due date,
+// soonest first, across every project they are a member of.
+//
+// Query params:
+//   - filter: overdue (open and past due) or week (due this week); default all
+//   - tz: IANA zone the week is computed in; default UTC
+//   - include_done: also list finished tasks (ignored for overdue)
+func (h *Handler) GetMyDueTasks(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	filter := strings.TrimSpace(c.Query("filter"))
+	if filter != "" && filter != "overdue" && filter != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter (want overdue or week)"})
+		return
+	}
+
+	loc := time.UTC
+	if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz"})
+			return
+		}
+		loc = l
+	}
+	includeDone := c.Query("include_done") == "true"
+
+	var from, to *time.Time
+	if filter == "week" {
+		start, end := weekBounds(time.Now(), loc)
+		from, to = &start, &end
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	rows, err := h.DB.Query(ctx, `
+		select `+taskColumnsAt("pos.sort_index")+`, p.name
+		from tasks t `+memberTaskPositions("$1")+`
+		join projects p on p.id = t.project_id
+		join projects_members pm on pm.project_id = t.project_id and pm.user_id = t.assignee_id
+		left join users usr on usr.id = t.assignee_id
+		where t.assignee_id = $1::uuid
+			and t.deleted_at is null
+			and t.due_at is not null
+			and ($2 or t.completed_at is null)
+			and ($3 <> 'overdue' or (t.due_at < now() and t.completed_at is null))
+			and ($4::timestamptz is null or t.due_at >= $4)
+			and ($5::timestamptz is null or t.due_at < $5)
+		order by t.due_at asc, t.created_at asc
+	`, uid, includeDone, filter, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	out := make([]MyTask, 0)
+	for rows.Next() {
+		var t MyTask
+		if err := scanTask(scanAlso(rows, &t.ProjectName), &t.Task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": out})
+}
+
+// sortKey is one column of a keyset-paginated ordering. Expr must never be
+// null, or rows would be skipped between pages; Type casts the cursor's
+// text value back for the comparison.
+type sortKey struct {
+	Expr string
+	Type string
+	Desc bool
+}
+
+// myTasksGrouping orders tasks into groups ahead of the sort; Key and Name
+// label each group in the summary.
+type myTasksGrouping struct {
+	Keys []sortKey
+	Key  string
+	Name string
+}
+
+var myTasksGroupings = map[string]myTasksGrouping{
+	"status": {
+		Keys: []sortKey{
+			{Expr: "case ps.category when 'todo' then 0 when 'active' then 1 else 2 end", Type: "int"},
+			{Expr: "t.status", Type: "text"},
+		},
+		Key:  "t.status",
+		Name: "min(ps.name)",
+	},
+	"project": {
+		Keys: []sortKey{
+			{Expr: "lower(p.name)", Type: "text"},
+			{Expr: "p.id", Type: "uuid"},
+		},
+		Key:  "p.id::text",
+		Name: "min(p.name)",
+	},
+}
+
+// Due dates sort soonest first with undated tasks last; difficulty hardest
+// first; created newest first.
+var myTasksSorts = map[string][]sortKey{
+	"due":        {{Expr: "coalesce(t.due_at, 'infinity')", Type: "timestamptz"}},
+	"difficulty": {{Expr: "t.difficulty", Type: "int", Desc: true}},
+	"created":    {{Expr: "t.created_at", Type: "timestamptz", Desc: true}},
+}
+
+// myTasksMatches follows "from tasks t" in the page and summary queries. $1
+// is the caller, $2 includes finished tasks.
+const myTasksMatches = `
+	join projects p on p.id = t.project_id
+	join projects_members pm on pm.project_id = t.project_id and pm.user_id = $1::uuid
+	left join project_statuses ps on ps.project_id = t.project_id and ps.key = t.status
+	left join users usr on usr.id = t.assignee_id
+	where t.assignee_id = $1::uuid
+		and t.deleted_at is null
+		and ($2 or t.completed_at is null)
+`
+
+func orderBy(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Expr
+		if k.Desc {
+			parts[i] += " desc"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetAfter matches the rows that sort after the one whose key values are
+// bound, as text, from $first on.
+func keysetAfter(keys []sortKey, first int) string {
+	param := func(i int) string {
+		return fmt.Sprintf("$%d::text::%s", first+i, keys[i].Type)
+	}
+	alts := make([]string, len(keys))
+	for i, k := range keys {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, keys[j].Expr+" = "+param(j))
+		}
+		op := " > "
+		if k.Desc {
+			op = " < "
+		}
+		conds = append(conds, k.Expr+op+param(i))
+		alts[i] = "(" + strings.Join(conds, " and ") + ")"
+	}
+	return "(" + strings.Join(alts, " or ") + ")"
+}
+
+// myTasksCursor is the last row of a page. It is only valid for the same
+// sort and grouping.
+type myTasksCursor struct {
+	Sort  string   `json:"s"`
+	Group string   `json:"g"`
+	Keys  []string `json:"k"`
+}
+
+func (cur myTasksCursor) encode() string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeMyTasksCursor(raw string) (myTasksCursor, error) {
+	var cur myTasksCursor
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(b, &cur)
+	return cur, err
+}
+
+// ListMyTasks lists the tasks assigned to the caller across every project
+// they are a member of, each with its project's name.
+//
+// Query params:
+//   - assignee: me (the only value for now; the default)
+//   - group_by: status or project; tasks come grouped and groups lists
+//     each group's size
+//   - sort: due (default), difficulty or created
+//   - include_done: also list finished tasks
+//   - limit, cursor: paging; pass next_cursor back to get the next page
+func (h *Handler) ListMyTasks(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	if a := strings.TrimSpace(c.Query("assignee")); a != "" && a != "me" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee (want me)"})
+		return
+	}
+
+	groupBy := strings.TrimSpace(c.Query("group_by"))
+	grouping, ok := myTasksGroupings[groupBy]
+	if !ok && groupBy != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group_by (want status or project)"})
+		return
+	}
+
+	sortBy := strings.TrimSpace(c.Query("sort"))
+	if sortBy == "" {
+		sortBy = "due"
+	}
+	sortKeys, ok := myTasksSorts[sortBy]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort (want due, difficulty or created)"})
+		return
+	}
+	includeDone := c.Query("include_done") == "true"
+
+	limit, ok := queryInt(c, "limit", defaultMyTasksLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxMyTasksLimit {
+		limit = maxMyTasksLimit
+	}
+
+	keys := append(append(append([]sortKey{}, grouping.Keys...), sortKeys...), sortKey{Expr: "t.id", Type: "uuid"})
+
+	args := []any{uid, includeDone}
+	after := "true"
+	if raw := strings.TrimSpace(c.Query("cursor")); raw != "" {
+		cur, err := decodeMyTasksCursor(raw)
+		if err != nil || cur.Sort != sortBy || cur.Group != groupBy || len(cur.Keys) != len(keys) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		after = keysetAfter(keys, len(args)+1)
+		for _, k := range cur.Keys {
+			args = append(args, k)
+		}
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	page := MyTasksPage{Tasks: []MyTask{}, Limit: limit}
+
+	// The summary covers every page, so it ignores the cursor.
+	if groupBy == "" {
+		if err := h.DB.QueryRow(ctx, `select count(*) from tasks t `+myTasksMatches, uid, includeDone).Scan(&page.Total); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	} else {
+		groupExprs := make([]string, len(grouping.Keys))
+		for i, k := range grouping.Keys {
+			groupExprs[i] = k.Expr
+		}
+		rows, err := h.DB.Query(ctx, `
+			select `+grouping.Key+`, coalesce(`+grouping.Name+`, `+grouping.Key+`), count(*)::int
+			from tasks t `+myTasksMatches+`
+			group by `+strings.Join(groupExprs, ", ")+`, `+grouping.Key+`
+			order by `+orderBy(grouping.Keys)+`
+		`, uid, includeDone)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		page.Groups = make([]MyTaskGroup, 0)
+		for rows.Next() {
+			var g MyTaskGroup
+			if err := rows.Scan(&g.Key, &g.Name, &g.Count); err != nil {
+				rows.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+			page.Groups = append(page.Groups, g)
+			page.Total += g.Count
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	keyCols := make([]string, len(keys))
+	for i, k := range keys {
+		keyCols[i] = "(" + k.Expr + ")::text"
+	}
+
+	// One row past the limit tells whether there is another page.
+	rows, err := h.DB.Query(ctx, `
+		select `+taskColumnsAt("pos.sort_index")+`, p.name, `+strings.Join(keyCols, ", ")+`
+		from tasks t `+memberTaskPositions("$1")+`
+		`+myTasksMatches+`
+			and `+after+`
+		order by `+orderBy(keys)+`
+		limit `+fmt.Sprintf("$%d", len(args)+1)+`
+	`, append(args, limit+1)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	var last []string
+	for rows.Next() {
+		if len(page.Tasks) == limit {
+			next := myTasksCursor{Sort: sortBy, Group: groupBy, Keys: last}.encode()
+			page.NextCursor = &next
+			break
+		}
+
+		var t MyTask
+		vals := make([]string, len(keys))
+		dest := []any{&t.ProjectName}
+		for i := range vals {
+			dest = append(dest, &vals[i])
+		}
+		if err := scanTask(scanAlso(rows, dest...), &t.Task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		page.Tasks = append(page.Tasks, t)
+		last = vals
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
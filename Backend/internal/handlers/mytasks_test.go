@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeysetAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []sortKey
+		first int
+		want  string
+	}{
+		{
+			name:  "single asc",
+			keys:  []sortKey{{Expr: "a", Type: "int"}},
+			first: 3,
+			want:  "((a > $3::text::int))",
+		},
+		{
+			name:  "single desc",
+			keys:  []sortKey{{Expr: "a", Type: "timestamptz", Desc: true}},
+			first: 1,
+			want:  "((a < $1::text::timestamptz))",
+		},
+		{
+			name: "asc then desc",
+			keys: []sortKey{
+				{Expr: "a", Type: "int"},
+				{Expr: "b", Type: "int", Desc: true},
+			},
+			first: 4,
+			want:  "((a > $4::text::int) or (a = $4::text::int and b < $5::text::int))",
+		},
+		{
+			name: "desc then asc then desc",
+			keys: []sortKey{
+				{Expr: "a", Type: "text", Desc: true},
+				{Expr: "b", Type: "uuid"},
+				{Expr: "c", Type: "int", Desc: true},
+			},
+			first: 2,
+			want: "((a < $2::text::text)" +
+				" or (a = $2::text::text and b > $3::text::uuid)" +
+				" or (a = $2::text::text and b = $3::text::uuid and c < $4::text::int))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keysetAfter(tt.keys, tt.first); got != tt.want {
+				t.Errorf("keysetAfter() =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		keys []sortKey
+		want string
+	}{
+		{keys: []sortKey{{Expr: "a"}}, want: "a"},
+		{keys: []sortKey{{Expr: "a", Desc: true}}, want: "a desc"},
+		{keys: []sortKey{{Expr: "a"}, {Expr: "b", Desc: true}, {Expr: "c"}}, want: "a, b desc, c"},
+	}
+	for _, tt := range tests {
+		if got := orderBy(tt.keys); got != tt.want {
+			t.Errorf("orderBy(%v) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestMyTasksCursor(t *testing.T) {
+	tests := []struct {
+		name string
+		cur  myTasksCursor
+	}{
+		{name: "no grouping", cur: myTasksCursor{Sort: "due", Keys: []string{"infinity", "0b6c0c1e-5a8e-4f5e-9d8b-2f0f4c1f7a10"}}},
+		{name: "grouped", cur: myTasksCursor{Sort: "difficulty", Group: "status", Keys: []string{"1", "in_progress", "4", "x"}}},
+		{name: "url-unsafe text", cur: myTasksCursor{Sort: "created", Group: "project", Keys: []string{"a/b+c?d=é", ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := tt.cur.encode()
+			for _, r := range raw {
+				if r == '+' || r == '/' || r == '=' {
+					t.Fatalf("encode() = %q is not URL-safe", raw)
+				}
+			}
+			got, err := decodeMyTasksCursor(raw)
+			if err != nil {
+				t.Fatalf("decodeMyTasksCursor(%q): %v", raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.cur) {
+				t.Errorf("round trip = %+v, want %+v", got, tt.cur)
+			}
+		})
+	}
+
+	for _, raw := range []string{"!!!", "bm90IGpzb24"} {
+		if _, err := decodeMyTasksCursor(raw); err == nil {
+			t.Errorf("decodeMyTasksCursor(%q) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// patchField is one member of an RFC 7396 merge patch: absent (Set is false),
+// an explicit null (Null is true), or a value.
+type patchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON is only called for members present in the document, including
+// explicit nulls, which is what separates "clear this" from "leave it alone".
+func (f *patchField[T]) UnmarshalJSON(b []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		f.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &f.Value)
+}
+
+var errPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// decodeMergePatch reads a merge-patch document into dst. Only object patches
+// are supported (a non-object patch would replace the whole resource), and
+// members dst does not declare are rejected rather than ignored.
+func decodeMergePatch(r io.Reader, dst any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '{' {
+		return errPatchNotObject
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -18,36 +19,56 @@ type Member struct {
 }
 
 type Project struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	OwnerId     string   `json:"owner_id"`
-	Members     []Member `json:"members"`
-	Tasks       []Task   `json:"tasks"`
-	IsPinned    bool     `json:"is_pinned"`
-	SortIndex   int      `json:"sort_index"`
+	ID          string           `json:"id"`
+	Key         string           `json:"key"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	OwnerId     string           `json:"owner_id"`
+	Members     []Member         `json:"members"`
+	Tasks       []Task           `json:"tasks"`
+	IsPinned    bool             `json:"is_pinned"`
+	SortIndex   int              `json:"sort_index"`
+	Workflow    []WorkflowStatus `json:"workflow"`
+	// Tags are the caller's own labels on this project.
+	Tags []ProjectTag `json:"tags"`
+	// Rank orders the owner's projects; SortIndex is the position.
+	Rank string `json:"rank"`
+	// Version goes up on every edit and is sent as the ETag.
+	Version int `json:"version"`
 }
 
 type EditProjectDetail struct {
 	ID          string `json:"id"`
+	Key         string `json:"key"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Version     int    `json:"version"`
 }
 
 // ========= Requests =========
 type createProjectReq struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Key prefixes task keys (FRG-42); derived from the name when empty.
+	Key string `json:"key"`
 }
 
 type editProjectDetailsReq struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Key renames the task key prefix when set; numbers stay the same.
+	Key string `json:"key"`
 }
 
+// reorderProjectsReq either lists project ids in their new order, or moves
+// one project after or before another.
 type reorderProjectsReq struct {
 	ProjectIDs []string `json:"project_ids"`
+
+	ProjectID *string `json:"project_id"`
+	AfterID   *string `json:"after_id"`
+	BeforeID  *string `json:"before_id"`
 }
 
 // Helper function to extract and validate user ID from context
@@ -71,6 +92,9 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		return
 	}
 
+	// ?tag=client&tag=q3 keeps projects carrying all of the named tags
+	tagFilter := parseTagFilter(c)
+
 	ctx, cancel := contextTimeout(c, 5*time.Second)
 	defer cancel()
 
@@ -84,16 +108,43 @@ func (h *Handler) GetProjects(c *gin.Context) {
 	rows, err := h.DB.Query(ctx, `
 		select
 			p.id::text,
+			p.key,
 			p.name,
 			p.description,
 			p.owner_id::text,
 			p.is_pinned,
-			p.sort_index
+			pos.sort_index,
+			p.rank,
+			p.version
 		from projects_members pm
 		join projects p on p.id = pm.project_id
+		-- each project's place in its owner's list, numbered once for the
+		-- owners of the caller's projects
+		join (
+			select o.id,
+				(row_number() over (partition by o.owner_id order by o.rank, o.id) - 1)::int as sort_index
+			from projects o
+			where o.owner_id in (
+				select op.owner_id
+				from projects_members om
+				join projects op on op.id = om.project_id
+				where om.user_id = $1
+			)
+		) pos on pos.id = p.id
 		where pm.user_id = $1
-		order by p.sort_index asc, p.created_at desc, lower(p.name) asc
-	`, userID)
+			and (
+				cardinality($2::text[]) = 0
+				or (
+					select count(*)
+					from project_tags pt
+					join tags tg on tg.id = pt.tag_id
+					where pt.project_id = p.id
+						and tg.user_id = pm.user_id
+						and lower(tg.name) = any($2::text[])
+				) = cardinality($2::text[])
+			)
+		order by p.rank asc, p.created_at desc, lower(p.name) asc
+	`, userID, tagFilter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
@@ -105,11 +156,12 @@ func (h *Handler) GetProjects(c *gin.Context) {
 
 	for rows.Next() {
 		var p Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.OwnerId, &p.IsPinned, &p.SortIndex); err != nil {
+		if err := rows.Scan(&p.ID, &p.Key, &p.Name, &p.Description, &p.OwnerId, &p.IsPinned, &p.SortIndex, &p.Rank, &p.Version); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 			return
 		}
 		p.Members = []Member{}
+		p.Tags = []ProjectTag{}
 		projects = append(projects, p)
 		projectIDs = append(projectIDs, p.ID)
 	}
@@ -166,33 +218,90 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		projects[i].Members = memberMap[projects[i].ID]
 	}
 
-	// 3) Fetch all tasks for those project IDs
+	// Attach the caller's tags
+	tagRows, err := h.DB.Query(ctx, `
+		select pt.project_id::text, tg.id::text, tg.name, tg.color
+		from project_tags pt
+		join tags tg on tg.id = pt.tag_id
+		where tg.user_id = $1::uuid
+			and pt.project_id::text = any($2)
+		order by lower(tg.name) asc
+	`, userID, projectIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tagRows.Close()
+
+	tagMap := make(map[string][]ProjectTag, len(projectIDs))
+
+	for tagRows.Next() {
+		var pid string
+		var t ProjectTag
+		if err := tagRows.Scan(&pid, &t.ID, &t.Name, &t.Color); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		tagMap[pid] = append(tagMap[pid], t)
+	}
+
+	if err := tagRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	for i := range projects {
+		if tags, ok := tagMap[projects[i].ID]; ok {
+			projects[i].Tags = tags
+		}
+	}
+
+	// 3) Fetch every project's workflow columns
+	wfRows, err := h.DB.Query(ctx, `
+		select project_id::text, key, name, category, sort_index, wip_limit, wip_per_assignee, wip_mode
+		from project_statuses
+		where project_id::text = any($1)
+		order by sort_index asc, created_at asc
+	`, projectIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer wfRows.Close()
+
+	workflowMap := make(map[string][]WorkflowStatus, len(projectIDs))
+
+	for wfRows.Next() {
+		var pid string
+		var s WorkflowStatus
+		if err := wfRows.Scan(&pid, &s.Key, &s.Name, &s.Category, &s.SortIndex, &s.WipLimit, &s.WipPerAssignee, &s.WipMode); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		workflowMap[pid] = append(workflowMap[pid], s)
+	}
+
+	if err := wfRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	for i := range projects {
+		projects[i].Workflow = workflowMap[projects[i].ID]
+	}
+
+	// 4) Fetch all tasks for those project IDs, in workflow column order
 	taskRows, err := h.DB.Query(ctx, `
-		select
-			t.project_id::text,
-			t.id::text,
-			t.title,
-			coalesce(t.details, ''),
-			t.status,
-			coalesce(t.assignee_id::text, ''),
-			coalesce(u.username, ''),
-			t.difficulty,
-			t.sort_index,
-			t.created_at
+		select `+taskColumnsAt(columnPosition)+`
 		from tasks t
-		left join users u on u.id = t.assignee_id
-		where t.project_id::text = any($1)
+		left join users usr on usr.id = t.assignee_id
+		left join project_statuses ps on ps.project_id = t.project_id and ps.key = t.status
+		where t.project_id::text = any($1) and t.deleted_at is null
 		order by
 			t.project_id::text asc,
-			case t.status
-				when 'backlog' then 1
-				when 'inProgress' then 2
-				when 'blocked' then 3
-				when 'done' then 4
-				else 9
-			end,
-			t.sort_index asc,
-			t.created_at asc
+			coalesce(ps.sort_index, 2147483647),
+			t.rank asc,
+			t.id asc
 	`, projectIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
@@ -204,38 +313,12 @@ func (h *Handler) GetProjects(c *gin.Context) {
 	taskMap := make(map[string][]Task, len(projectIDs))
 
 	for taskRows.Next() {
-		var pid string
 		var t Task
-		var assigneeID string
-		var assigneeUsername string
-		var createdAt time.Time
-
-		if err := taskRows.Scan(
-			&pid,
-			&t.ID,
-			&t.Title,
-			&t.Details,
-			&t.Status,
-			&assigneeID,
-			&assigneeUsername,
-			&t.Difficulty,
-			&t.SortIndex,
-			&createdAt,
-		); err != nil {
+		if err := scanTask(taskRows, &t); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 			return
 		}
-
-		t.ProjectID = pid
-		if assigneeID != "" {
-			t.AssigneeID = &assigneeID
-		}
-		if assigneeUsername != "" {
-			t.AssigneeUsername = &assigneeUsername
-		}
-		t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
-
-		taskMap[pid] = append(taskMap[pid], t)
+		taskMap[t.ProjectID] = append(taskMap[t.ProjectID], t)
 	}
 
 	if err := taskRows.Err(); err != nil {
@@ -249,6 +332,15 @@ func (h *Handler) GetProjects(c *gin.Context) {
 		if projects[i].Tasks == nil {
 			projects[i].Tasks = []Task{}
 		}
+
+		// Column counts sit next to the WIP limits on each status.
+		counts := make(map[string]int, len(projects[i].Workflow))
+		for _, t := range projects[i].Tasks {
+			counts[t.Status]++
+		}
+		for j := range projects[i].Workflow {
+			projects[i].Workflow[j].Count = counts[projects[i].Workflow[j].Key]
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -300,24 +392,45 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	}
 	defer tx.Rollback(ctx)
 
+	key := ""
+	if strings.TrimSpace(req.Key) != "" {
+		key, err = normalizeProjectKey(req.Key)
+	} else {
+		key, err = deriveProjectKey(ctx, tx, name)
+	}
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	list := projectRankList(ownerID)
+	if err := lockRankScope(ctx, tx, list.Scope); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	rank, err := placeRank(ctx, tx, list, nil, rankPlace{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
 	var projectID string
 	var sortIndex int
-	if err := h.DB.QueryRow(ctx,
-		`insert into projects (name, description, owner_id, sort_index)
-		values (
-			$1,
-			$2,
-			$3,
-			coalesce((select max(sort_index) + 1 from projects where owner_id = $3), 0)
-			)
-		returning id::text, sort_index
-	`, name, req.Description, ownerID).Scan(&projectID, &sortIndex); err != nil {
+	if err := tx.QueryRow(ctx,
+		`insert into projects (name, description, owner_id, rank, key)
+		values ($1, $2, $3, $4, $5)
+		returning id::text, (select count(*)::int from projects where owner_id = $3)
+	`, name, req.Description, ownerID, rank, key).Scan(&projectID, &sortIndex); err != nil {
+		if isProjectKeyConflict(err) {
+			writeError(c, errProjectKeyTaken)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
 
 	var members Member
-	if err := h.DB.QueryRow(ctx,
+	if err := tx.QueryRow(ctx,
 		`insert into projects_members (project_id, user_id, username, roleKey)
 		values ($1, $2, $3, $4)
 		returning user_id::text, username, roleKey
@@ -326,6 +439,11 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		return
 	}
 
+	if err := insertWorkflow(ctx, tx, projectID, defaultWorkflow); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
@@ -333,6 +451,7 @@ func (h *Handler) CreateProject(c *gin.Context) {
 
 	c.JSON(http.StatusOK, Project{
 		ID:          projectID,
+		Key:         key,
 		Name:        name,
 		Description: req.Description,
 		OwnerId:     ownerID,
@@ -340,6 +459,10 @@ func (h *Handler) CreateProject(c *gin.Context) {
 		Tasks:       []Task{},
 		IsPinned:    false,
 		SortIndex:   sortIndex,
+		Workflow:    defaultWorkflow,
+		Tags:        []ProjectTag{},
+		Rank:        rank,
+		Version:     1,
 	})
 }
 
@@ -360,6 +483,10 @@ func (h *Handler) EditProjectDetails(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
 		return
 	}
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
 
 	name := strings.TrimSpace(req.Name)
 	if name == "" {
@@ -369,6 +496,16 @@ func (h *Handler) EditProjectDetails(c *gin.Context) {
 
 	description := strings.TrimSpace(req.Description)
 
+	var key *string
+	if strings.TrimSpace(req.Key) != "" {
+		k, err := normalizeProjectKey(req.Key)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		key = &k
+	}
+
 	ctx, cancel := contextTimeout(c, 5*time.Second)
 	defer cancel()
 
@@ -380,24 +517,58 @@ func (h *Handler) EditProjectDetails(c *gin.Context) {
 	}
 	defer tx.Rollback(ctx)
 
+	// Lock the row and compare versions before writing
+	var current EditProjectDetail
+	if err := tx.QueryRow(ctx,
+		`select id::text, key, name, description, version
+		from projects
+		where id = $1::uuid
+		and owner_id = $2
+		for update
+	`, id, ownerID).Scan(&current.ID, &current.Key, &current.Name, &current.Description, &current.Version); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if !ifMatch(c, current.Version) {
+		setETag(c, current.Version)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "project was changed by someone else", "current": current})
+		return
+	}
+
 	var updated EditProjectDetail
-	if err := h.DB.QueryRow(ctx,
+	if err := tx.QueryRow(ctx,
 		`update projects 
 		set name = $1, 
-		description = $2
+		description = $2,
+		key = coalesce($5, key),
+		version = version + 1
 		where id = $3::uuid 
 		and owner_id = $4
-		returning id::text, name, description
-	`, name, description, id, ownerID).Scan(&updated.ID, &updated.Name, &updated.Description); err != nil {
+		returning id::text, key, name, description, version
+	`, name, description, id, ownerID, key).Scan(&updated.ID, &updated.Key, &updated.Name, &updated.Description, &updated.Version); err != nil {
 		if err == pgx.ErrNoRows {
 			fmt.Print(err)
 			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
 			return
 		}
+		if isProjectKeyConflict(err) {
+			writeError(c, errProjectKeyTaken)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	setETag(c, updated.Version)
 	c.JSON(http.StatusOK, updated)
 }
 
@@ -412,6 +583,10 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
 		return
 	}
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
 
 	ctx, cancel := contextTimeout(c, 5*time.Second)
 	defer cancel()
@@ -423,10 +598,22 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 	}
 	defer tx.Rollback(ctx)
 
-	cmd, err := h.DB.Exec(ctx,
+	projectID, err := uuid.Parse(strings.ToLower(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	blobKeys, err := attachmentKeys(ctx, tx, projectID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	cmd, err := tx.Exec(ctx,
 		`delete from projects 
 		where id = $1::uuid and owner_id = $2
-	`, id, ownerID)
+	`, projectID, ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
@@ -437,6 +624,12 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	h.removeBlobs(blobKeys)
+
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
@@ -455,6 +648,10 @@ func (h *Handler) PinProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing id"})
 		return
 	}
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
 
 	pin := strings.TrimSpace(c.Param("pin"))
 	if pin == "" {
@@ -478,7 +675,8 @@ func (h *Handler) PinProject(c *gin.Context) {
 
 	cmd, err := h.DB.Exec(ctx,
 		`update projects 
-		set is_pinned = $1::boolean
+		set is_pinned = $1::boolean,
+		version = version + 1
 		where id = $2::uuid and owner_id = $3
 	`, pin, id, ownerID)
 	if err != nil {
@@ -506,6 +704,11 @@ func (h *Handler) ReorderProjects(c *gin.Context) {
 		return
 	}
 
+	if req.ProjectID != nil {
+		h.moveProject(c, ownerID, req)
+		return
+	}
+
 	if len(req.ProjectIDs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing project_ids"})
 		return
@@ -516,7 +719,7 @@ func (h *Handler) ReorderProjects(c *gin.Context) {
 	seen := make(map[string]struct{}, len(req.ProjectIDs))
 	for _, raw := range req.ProjectIDs {
 		id := strings.ToLower(strings.TrimSpace(raw))
-		if id == "" {
+		if _, err := uuid.Parse(id); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
 			return
 		}
@@ -555,17 +758,22 @@ func (h *Handler) ReorderProjects(c *gin.Context) {
 		return
 	}
 
-	// Update sort_index based on the provided order
+	if err := lockRankScope(ctx, tx, projectRankScope(ownerID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Give the listed projects evenly spaced ranks in the provided order
 	cmd, err := tx.Exec(ctx, `
-        with ord(pid, ord) as (
-			select * from unnest($1::uuid[]) with ordinality
+        with ord(pid, rank) as (
+			select * from unnest($1::uuid[], $3::text[])
         )
         update projects p
-        set sort_index = (ord.ord - 1)
+        set rank = ord.rank
         from ord
         where p.id = ord.pid
 			and p.owner_id = $2::uuid
-    `, ids, ownerID)
+    `, ids, ownerID, evenRanks(len(ids)))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
@@ -583,3 +791,64 @@ func (h *Handler) ReorderProjects(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// moveProject places one of the owner's projects after or before another,
+// rewriting only that project's rank.
+func (h *Handler) moveProject(c *gin.Context, ownerID string, req reorderProjectsReq) {
+	projectID, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*req.ProjectID)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	place, err := parseRankPlace(nil, req.AfterID, req.BeforeID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if !place.isSet() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing after_id or before_id"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	list := projectRankList(ownerID)
+	if err := lockRankScope(ctx, tx, list.Scope); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	rank, err := placeRank(ctx, tx, list, &projectID, place)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	cmd, err := tx.Exec(ctx, `
+		update projects
+		set rank = $3
+		where id = $1 and owner_id = $2::uuid
+	`, projectID, ownerID, rank)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "rank": rank})
+}
@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tasks and projects are ordered by rank: a base-36 string read as a fraction
+// (0.<digits>) and compared bytewise, so a row can always be given a rank
+// between two others and a move writes only the moved row. Ranks never end
+// in '0', which keeps every fraction's spelling unique.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// Columns whose ranks grow past rankMaxLen (after many inserts into the same
+// gap) are respaced by the rebalancer.
+const rankMaxLen = 12
+
+var errRankOrder = errors.New("ranks out of order")
+
+func validRank(s string) bool {
+	if s == "" || s[len(s)-1] == '0' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(rankDigits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rankBetween returns a rank strictly between a and b. "" stands for the
+// start (a) or the end (b) of the list. It fails with errRankOrder when
+// a >= b, which happens when two rows share a rank; rebalance and retry.
+func rankBetween(a, b string) (string, error) {
+	if (a != "" && !validRank(a)) || (b != "" && !validRank(b)) {
+		return "", fmt.Errorf("invalid rank %q or %q", a, b)
+	}
+	if a != "" && b != "" && a >= b {
+		return "", errRankOrder
+	}
+	return rankMidpoint(a, b), nil
+}
+
+func rankMidpoint(a, b string) string {
+	digit := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return '0'
+	}
+	tail := func(s string, i int) string {
+		if i < len(s) {
+			return s[i:]
+		}
+		return ""
+	}
+
+	// Share the common prefix, then work on what follows it.
+	if b != "" {
+		n := 0
+		for n < len(b) && digit(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(tail(a, n), b[n:])
+		}
+	}
+
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(rankDigits, a[0])
+	}
+	db := len(rankDigits)
+	if b != "" {
+		db = strings.IndexByte(rankDigits, b[0])
+	}
+	if db-da > 1 {
+		return string(rankDigits[(da+db+1)/2])
+	}
+	// Adjacent first digits: b's first digit alone still sorts before b,
+	// otherwise keep a's digit and go one level deeper.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankDigits[da]) + rankMidpoint(tail(a, 1), "")
+}
+
+// evenRanks returns n ascending ranks spread evenly over the whole range,
+// short enough to leave room for many inserts between neighbours.
+func evenRanks(n int) []string {
+	base := uint64(len(rankDigits))
+	width, span := 2, base*base
+	for span/uint64(n+1) < base*base && width < 12 {
+		width++
+		span *= base
+	}
+	step := span / uint64(n+1)
+
+	out := make([]string, n)
+	buf := make([]byte, width)
+	for i := range out {
+		v := step * uint64(i+1)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%base]
+			v /= base
+		}
+		out[i] = strings.TrimRight(string(buf), "0")
+	}
+	return out
+}
+
+// lockRankScope serializes rank changes within one list (a task column or an
+// owner's projects) until tx ends, so concurrent moves can't pick the same
+// gap or race the rebalancer.
+func lockRankScope(ctx context.Context, tx pgx.Tx, scope string) error {
+	_, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext($1))`, scope)
+	return err
+}
+
+func taskRankScope(projectID uuid.UUID, status string) string {
+	return "tasks:" + projectID.String() + ":" + status
+}
+
+func projectRankScope(ownerID string) string {
+	return "projects:" + ownerID
+}
+
+// rankPlace says where a row goes in its list. AfterID and BeforeID name
+// neighbours; Index is a 0-based position, clamped to the list. All unset
+// appends to the end.
+type rankPlace struct {
+	Index    *int
+	AfterID  *uuid.UUID
+	BeforeID *uuid.UUID
+}
+
+func (p rankPlace) isSet() bool {
+	return p.Index != nil || p.AfterID != nil || p.BeforeID != nil
+}
+
+// parseRankPlace validates the position fields shared by the create and move
+// requests.
+func parseRankPlace(index *int, afterID, beforeID *string) (rankPlace, error) {
+	var p rankPlace
+	if index != nil {
+		if *index < 0 {
+			return p, newAPIError(http.StatusBadRequest, "invalid sort_index")
+		}
+		if afterID != nil || beforeID != nil {
+			return p, newAPIError(http.StatusBadRequest, "use either sort_index or after_id/before_id")
+		}
+		i := *index
+		p.Index = &i
+	}
+	parse := func(s *string, name string) (*uuid.UUID, error) {
+		if s == nil {
+			return nil, nil
+		}
+		id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*s)))
+		if err != nil {
+			return nil, newAPIError(http.StatusBadRequest, "invalid "+name)
+		}
+		return &id, nil
+	}
+	var err error
+	if p.AfterID, err = parse(afterID, "after_id"); err != nil {
+		return p, err
+	}
+	if p.BeforeID, err = parse(beforeID, "before_id"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// rankList is one ordered list: the rows of Table matching Where, whose
+// placeholders $1..$n are bound to Args.
+type rankList struct {
+	Table string // tasks | projects
+	Where string
+	Args  []any
+	Scope string
+}
+
+func taskRankList(projectID uuid.UUID, status string) rankList {
+	return rankList{
+		Table: "tasks",
+		Where: "project_id = $1 and status = $2 and deleted_at is null",
+		Args:  []any{projectID, status},
+		Scope: taskRankScope(projectID, status),
+	}
+}
+
+func projectRankList(ownerID string) rankList {
+	return rankList{
+		Table: "projects",
+		Where: "owner_id = $1::uuid",
+		Args:  []any{ownerID},
+		Scope: projectRankScope(ownerID),
+	}
+}
+
+// param returns the placeholder for the i-th (1-based) argument after the
+// list's own.
+func (l rankList) param(i int) string {
+	return "$" + strconv.Itoa(len(l.Args)+i)
+}
+
+// args appends extra arguments to the list's own.
+func (l rankList) args(extra ...any) []any {
+	return append(append([]any{}, l.Args...), extra...)
+}
+
+// placeRank picks a rank for self (nil for a new row) at place in list.
+// Call it after lockRankScope on the list.
+func placeRank(ctx context.Context, tx pgx.Tx, list rankList, self *uuid.UUID, place rankPlace) (string, error) {
+	for attempt := 0; ; attempt++ {
+		a, b, err := rankNeighbours(ctx, tx, list, self, place)
+		if err != nil {
+			return "", err
+		}
+		r, err := rankBetween(a, b)
+		if errors.Is(err, errRankOrder) && attempt == 0 {
+			// Tied neighbours: spread the list out and look again.
+			if err := rebalanceRanks(ctx, tx, list); err != nil {
+				return "", err
+			}
+			continue
+		}
+		return r, err
+	}
+}
+
+// rankNeighbours returns the ranks the placed row must fall between.
+func rankNeighbours(ctx context.Context, tx pgx.Tx, list rankList, self *uuid.UUID, place rankPlace) (string, string, error) {
+	exclude := uuid.Nil
+	if self != nil {
+		exclude = *self
+	}
+	from := `from ` + list.Table + ` where ` + list.Where + ` and id <> ` + list.param(1)
+
+	// rankOf looks up a named neighbour, which must be in the list.
+	rankOf := func(id uuid.UUID, name string) (string, error) {
+		var r string
+		err := tx.QueryRow(ctx, `select rank `+from+` and id = `+list.param(2),
+			list.args(exclude, id)...).Scan(&r)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", newAPIError(http.StatusBadRequest, name+" is not in the target list")
+		}
+		return r, err
+	}
+	// edge returns the rank next to (r, id) in one direction, or "".
+	edge := func(op, dir string, r string, id uuid.UUID) (string, error) {
+		var out string
+		err := tx.QueryRow(ctx, `select rank `+from+`
+			and (rank, id) `+op+` (`+list.param(2)+`::text collate "C", `+list.param(3)+`::uuid)
+			order by rank `+dir+`, id `+dir+` limit 1`,
+			list.args(exclude, r, id)...).Scan(&out)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return out, err
+	}
+
+	var a, b string
+	var err error
+	switch {
+	case place.AfterID != nil && place.BeforeID != nil:
+		if a, err = rankOf(*place.AfterID, "after_id"); err != nil {
+			return "", "", err
+		}
+		if b, err = rankOf(*place.BeforeID, "before_id"); err != nil {
+			return "", "", err
+		}
+		if a > b || (a == b && place.AfterID.String() >= place.BeforeID.String()) {
+			return "", "", newAPIError(http.StatusBadRequest, "after_id must come before before_id")
+		}
+	case place.AfterID != nil:
+		if a, err = rankOf(*place.AfterID, "after_id"); err != nil {
+			return "", "", err
+		}
+		b, err = edge(">", "asc", a, *place.AfterID)
+	case place.BeforeID != nil:
+		if b, err = rankOf(*place.BeforeID, "before_id"); err != nil {
+			return "", "", err
+		}
+		a, err = edge("<", "desc", b, *place.BeforeID)
+	case place.Index != nil && *place.Index == 0:
+		err = tx.QueryRow(ctx, `select coalesce(min(rank), '') `+from,
+			list.args(exclude)...).Scan(&b)
+	case place.Index != nil:
+		// The row at Index-1 goes before us and the next one after; past
+		// the end this finds nothing and we append.
+		var ranks []string
+		err = tx.QueryRow(ctx, `select array(select rank `+from+`
+			order by rank, id offset `+list.param(2)+` limit 2)`,
+			list.args(exclude, *place.Index-1)...).Scan(&ranks)
+		if err == nil && len(ranks) > 0 {
+			a = ranks[0]
+			if len(ranks) > 1 {
+				b = ranks[1]
+			}
+			break
+		}
+		if err != nil {
+			break
+		}
+		fallthrough
+	default:
+		err = tx.QueryRow(ctx, `select coalesce(max(rank), '') `+from,
+			list.args(exclude)...).Scan(&a)
+	}
+	return a, b, err
+}
+
+// rebalanceRanks respaces every rank in list evenly, keeping the order. Call
+// it with the list's scope locked. Versions are left alone: nothing a client
+// can see changes.
+func rebalanceRanks(ctx context.Context, tx pgx.Tx, list rankList) error {
+	var ids []uuid.UUID
+	if err := tx.QueryRow(ctx, `select array(select id from `+list.Table+` where `+list.Where+`
+		order by rank, id)`, list.Args...).Scan(&ids); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		update `+list.Table+` x
+		set rank = r.rank
+		from unnest($1::uuid[], $2::text[]) as r(id, rank)
+		where x.id = r.id and x.rank is distinct from r.rank
+	`, ids, evenRanks(len(ids)))
+	return err
+}
+
+// RebalanceRanks respaces every task column and project list whose ranks
+// have grown past rankMaxLen. Each list is done in its own transaction.
+func (h *Handler) RebalanceRanks(ctx context.Context) error {
+	var lists []rankList
+
+	rows, err := h.DB.Query(ctx, `
+		select project_id, status
+		from tasks
+		where deleted_at is null
+		group by project_id, status
+		having max(length(rank)) > $1
+	`, rankMaxLen)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var projectID uuid.UUID
+		var status string
+		if err := rows.Scan(&projectID, &status); err != nil {
+			rows.Close()
+			return err
+		}
+		lists = append(lists, taskRankList(projectID, status))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = h.DB.Query(ctx, `
+		select owner_id::text
+		from projects
+		group by owner_id
+		having max(length(rank)) > $1
+	`, rankMaxLen)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var ownerID string
+		if err := rows.Scan(&ownerID); err != nil {
+			rows.Close()
+			return err
+		}
+		lists = append(lists, projectRankList(ownerID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, list := range lists {
+		if err := pgx.BeginFunc(ctx, h.DB, func(tx pgx.Tx) error {
+			if err := lockRankScope(ctx, tx, list.Scope); err != nil {
+				return err
+			}
+			return rebalanceRanks(ctx, tx, list)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunRankRebalancer calls RebalanceRanks now and then every interval until
+// ctx is done.
+func (h *Handler) RunRankRebalancer(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if err := h.RebalanceRanks(runCtx); err != nil {
+			log.Printf("rank rebalance: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "empty list", a: "", b: "", want: "i"},
+		{name: "before first", a: "", b: "i", want: "9"},
+		{name: "after last", a: "i", b: "", want: "r"},
+		{name: "adjacent digits", a: "a", b: "b", want: "ai"},
+		{name: "shared prefix", a: "1", b: "11", want: "10i"},
+		{name: "b one digit longer", a: "a", b: "b5", want: "b"},
+		{name: "after z", a: "z", b: "", want: "zi"},
+		{name: "before 1", a: "", b: "1", want: "0i"},
+		{name: "wide gap deeper", a: "a1", b: "a9", want: "a5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rankBetween(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("rankBetween(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if got != tt.want {
+				t.Errorf("rankBetween(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			if !validRank(got) {
+				t.Errorf("rankBetween(%q, %q) = %q is not a valid rank", tt.a, tt.b, got)
+			}
+			if (tt.a != "" && got <= tt.a) || (tt.b != "" && got >= tt.b) {
+				t.Errorf("rankBetween(%q, %q) = %q is not between them", tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestRankBetweenErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		order bool // want errRankOrder
+	}{
+		{name: "equal", a: "i", b: "i", order: true},
+		{name: "reversed", a: "r", b: "i", order: true},
+		{name: "trailing zero", a: "i0", b: ""},
+		{name: "upper case", a: "", b: "A"},
+		{name: "punctuation", a: "a-", b: "z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rankBetween(tt.a, tt.b)
+			if err == nil {
+				t.Fatalf("rankBetween(%q, %q) succeeded", tt.a, tt.b)
+			}
+			if errors.Is(err, errRankOrder) != tt.order {
+				t.Errorf("rankBetween(%q, %q) = %v, errRankOrder wanted: %v", tt.a, tt.b, err, tt.order)
+			}
+		})
+	}
+}
+
+// Repeated inserts into the same gap must keep finding a rank between the
+// neighbours, whichever side of the gap they land on.
+func TestRankBetweenRepeatedInserts(t *testing.T) {
+	tests := []struct {
+		name string
+		next func(lo, hi, got string) (string, string)
+	}{
+		{name: "always at the front", next: func(lo, hi, got string) (string, string) { return lo, got }},
+		{name: "always at the end", next: func(lo, hi, got string) (string, string) { return got, hi }},
+		{name: "alternating", next: func() func(lo, hi, got string) (string, string) {
+			i := 0
+			return func(lo, hi, got string) (string, string) {
+				i++
+				if i%2 == 0 {
+					return lo, got
+				}
+				return got, hi
+			}
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lo, hi := "", ""
+			for i := 0; i < 200; i++ {
+				got, err := rankBetween(lo, hi)
+				if err != nil {
+					t.Fatalf("insert %d: rankBetween(%q, %q): %v", i, lo, hi, err)
+				}
+				if !validRank(got) || (lo != "" && got <= lo) || (hi != "" && got >= hi) {
+					t.Fatalf("insert %d: rankBetween(%q, %q) = %q", i, lo, hi, got)
+				}
+				lo, hi = tt.next(lo, hi, got)
+			}
+		})
+	}
+}
+
+func TestEvenRanks(t *testing.T) {
+	tests := []struct {
+		n      int
+		maxLen int
+	}{
+		{n: 1, maxLen: 2},
+		{n: 2, maxLen: 2},
+		{n: 35, maxLen: 2},
+		{n: 1000, maxLen: 4},
+		{n: 100000, maxLen: 6},
+	}
+	for _, tt := range tests {
+		got := evenRanks(tt.n)
+		if len(got) != tt.n {
+			t.Fatalf("evenRanks(%d) returned %d ranks", tt.n, len(got))
+		}
+		for i, r := range got {
+			if !validRank(r) {
+				t.Fatalf("evenRanks(%d)[%d] = %q is not a valid rank", tt.n, i, r)
+			}
+			if len(r) > tt.maxLen {
+				t.Errorf("evenRanks(%d)[%d] = %q is longer than %d", tt.n, i, r, tt.maxLen)
+			}
+			if i > 0 && got[i-1] >= r {
+				t.Fatalf("evenRanks(%d) not ascending at %d: %q >= %q", tt.n, i, got[i-1], r)
+			}
+		}
+		// Respaced lists must leave room before the first and after the last.
+		if _, err := rankBetween("", got[0]); err != nil {
+			t.Errorf("evenRanks(%d): no room before %q: %v", tt.n, got[0], err)
+		}
+		if _, err := rankBetween(got[len(got)-1], ""); err != nil {
+			t.Errorf("evenRanks(%d): no room after %q: %v", tt.n, got[len(got)-1], err)
+		}
+	}
+}
@@ -0,0 +1,966 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// A recurring task belongs to a series: a template (title, assignee, ...) and
+// a rule saying when the next occurrence is due. A new occurrence is created
+// in the first todo column when the previous one is completed or when its
+// date arrives, whichever comes first. Each occurrence is an ordinary task;
+// editing it changes only that occurrence, while PATCH .../series edits the
+// template and every open occurrence.
+
+const (
+	maxRecurInterval = 365
+	maxRecurCount    = 1000
+
+	// how many dates GET .../recurrence lists ahead
+	upcomingOccurrences = 5
+)
+
+const (
+	freqDaily   = "DAILY"
+	freqWeekly  = "WEEKLY"
+	freqMonthly = "MONTHLY"
+)
+
+// recurrence is the supported subset of an RFC 5545 RRULE: FREQ (DAILY,
+// WEEKLY or MONTHLY), INTERVAL, and at most one of COUNT and UNTIL.
+type recurrence struct {
+	Freq     string
+	Interval int
+	Count    int        // occurrences in all, counting the first; 0 means no limit
+	Until    *time.Time // last date an occurrence may fall on
+}
+
+func rruleError(reason string) error {
+	return &apiError{Status: http.StatusBadRequest, Body: gin.H{
+		"error":  "invalid rrule",
+		"reason": reason,
+	}}
+}
+
+// parseRRule reads a rule such as "FREQ=WEEKLY;INTERVAL=2;COUNT=10". A
+// leading "RRULE:" is allowed.
+func parseRRule(s string) (recurrence, error) {
+	r := recurrence{Interval: 1}
+
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return r, newAPIError(http.StatusBadRequest, "missing rrule")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, rruleError("expected NAME=VALUE, got " + part)
+		}
+		if seen[k] {
+			return r, rruleError(k + " given twice")
+		}
+		seen[k] = true
+
+		switch k {
+		case "FREQ":
+			if v != freqDaily && v != freqWeekly && v != freqMonthly {
+				return r, rruleError("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			r.Freq = v
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxRecurInterval {
+				return r, rruleError("INTERVAL must be between 1 and " + strconv.Itoa(maxRecurInterval))
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxRecurCount {
+				return r, rruleError("COUNT must be between 1 and " + strconv.Itoa(maxRecurCount))
+			}
+			r.Count = n
+		case "UNTIL":
+			d, err := time.Parse("20060102", v)
+			if err != nil {
+				t, terr := time.Parse("20060102T150405Z", v)
+				if terr != nil {
+					return r, rruleError("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+				}
+				d = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			r.Until = &d
+		default:
+			return r, rruleError(k + " is not supported")
+		}
+	}
+
+	if r.Freq == "" {
+		return r, rruleError("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return r, rruleError("COUNT and UNTIL can't both be set")
+	}
+	return r, nil
+}
+
+// String is the rule in canonical form, as stored.
+func (r recurrence) String() string {
+	s := "FREQ=" + r.Freq
+	if r.Interval > 1 {
+		s += ";INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	if r.Count > 0 {
+		s += ";COUNT=" + strconv.Itoa(r.Count)
+	}
+	if r.Until != nil {
+		s += ";UNTIL=" + r.Until.Format("20060102")
+	}
+	return s
+}
+
+// nth is the date of occurrence n of a series starting on start (n = 0 is
+// start itself), and false once the rule has run out. Monthly rules keep
+// start's day of month, falling back to the last day of shorter months.
+func (r recurrence) nth(start time.Time, n int) (time.Time, bool) {
+	if r.Count > 0 && n >= r.Count {
+		return time.Time{}, false
+	}
+
+	var d time.Time
+	switch r.Freq {
+	case freqDaily:
+		d = start.AddDate(0, 0, n*r.Interval)
+	case freqWeekly:
+		d = start.AddDate(0, 0, 7*n*r.Interval)
+	case freqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		d = first.AddDate(0, 0, min(start.Day(), last)-1)
+	}
+
+	if r.Until != nil && d.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// following finds the first occurrence that falls after day.
+func (r recurrence) following(start, day time.Time) (int, time.Time, bool) {
+	for n := 0; ; n++ {
+		d, ok := r.nth(start, n)
+		if !ok {
+			return n, d, false
+		}
+		if d.After(day) {
+			return n, d, true
+		}
+	}
+}
+
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ========= Series DTOs (responses) =========
+type TaskSeries struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	RRule     string `json:"rrule"`
+	StartsOn  string `json:"starts_on"` // YYYY-MM-DD of the first occurrence
+	// NextOn is when the next occurrence is due; null once the rule has run
+	// out or the series was stopped.
+	NextOn   *string  `json:"next_on"`
+	Upcoming []string `json:"upcoming"`
+
+	// Template every new occurrence is created from.
+	Title          string   `json:"title"`
+	Details        string   `json:"details"`
+	AssigneeID     *string  `json:"assignee_id"`
+	Difficulty     int      `json:"difficulty"`
+	EpicID         *string  `json:"epic_id"`
+	EstimateHours  *float64 `json:"estimate_hours"`
+	RequiredSkills []string `json:"required_skills"`
+	RoleKey        *string  `json:"role_key"`
+
+	Occurrences int    `json:"occurrences"` // tasks in the series
+	CreatedAt   string `json:"created_at"`
+}
+
+// ========= Requests =========
+type setRecurrenceReq struct {
+	RRule    string  `json:"rrule"`
+	StartsOn *string `json:"starts_on"` // YYYY-MM-DD; defaults to the task's date or today
+}
+
+// seriesPatch is a merge patch for a series' template. The same change is
+// applied to each of its open occurrences.
+type seriesPatch struct {
+	Title          patchField[string]   `json:"title"`
+	Details        patchField[string]   `json:"details"`
+	AssigneeID     patchField[string]   `json:"assignee_id"`
+	Difficulty     patchField[int]      `json:"difficulty"`
+	EpicID         patchField[string]   `json:"epic_id"`
+	EstimateHours  patchField[float64]  `json:"estimate_hours"`
+	RequiredSkills patchField[[]string] `json:"required_skills"`
+	RoleKey        patchField[string]   `json:"role_key"`
+}
+
+func (p seriesPatch) change() (taskChange, error) {
+	return updateTaskPatch{
+		Title:          p.Title,
+		Details:        p.Details,
+		AssigneeID:     p.AssigneeID,
+		Difficulty:     p.Difficulty,
+		EpicID:         p.EpicID,
+		EstimateHours:  p.EstimateHours,
+		RequiredSkills: p.RequiredSkills,
+		RoleKey:        p.RoleKey,
+	}.change()
+}
+
+// seriesColumns is the select list scanSeries expects, with the series
+// aliased as "s".
+const seriesColumns = `
+	s.id::text,
+	s.project_id::text,
+	s.rrule,
+	s.starts_on,
+	s.next_index,
+	s.next_on,
+	s.title,
+	s.details,
+	s.assignee_id::text,
+	s.difficulty,
+	s.epic_id::text,
+	s.estimate_hours::float8,
+	s.required_skills,
+	s.role_key,
+	(select count(*)::int from tasks t where t.series_id = s.id and t.deleted_at is null),
+	s.created_at
+`
+
+func scanSeries(row pgx.Row, s *TaskSeries) error {
+	var startsOn, createdAt time.Time
+	var nextOn *time.Time
+	var nextIndex int
+	if err := row.Scan(
+		&s.ID,
+		&s.ProjectID,
+		&s.RRule,
+		&startsOn,
+		&nextIndex,
+		&nextOn,
+		&s.Title,
+		&s.Details,
+		&s.AssigneeID,
+		&s.Difficulty,
+		&s.EpicID,
+		&s.EstimateHours,
+		&s.RequiredSkills,
+		&s.RoleKey,
+		&s.Occurrences,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+	s.StartsOn = startsOn.Format(time.DateOnly)
+	s.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+
+	s.Upcoming = []string{}
+	if nextOn == nil {
+		return nil
+	}
+	next := nextOn.Format(time.DateOnly)
+	s.NextOn = &next
+	rule, err := parseRRule(s.RRule)
+	if err != nil {
+		return err
+	}
+	for n := nextIndex; len(s.Upcoming) < upcomingOccurrences; n++ {
+		d, ok := rule.nth(startsOn, n)
+		if !ok {
+			break
+		}
+		s.Upcoming = append(s.Upcoming, d.Format(time.DateOnly))
+	}
+	return nil
+}
+
+func loadSeries(ctx context.Context, q dbtx, seriesID uuid.UUID) (TaskSeries, error) {
+	var s TaskSeries
+	err := scanSeries(q.QueryRow(ctx, `
+		select `+seriesColumns+`
+		from task_series s
+		where s.id = $1
+	`, seriesID), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, newAPIError(http.StatusNotFound, "series not found")
+	}
+	return s, err
+}
+
+// taskSeriesID returns the series a task belongs to, or 404 when the task
+// doesn't recur.
+func taskSeriesID(ctx context.Context, q dbtx, projectID, taskID uuid.UUID) (uuid.UUID, error) {
+	var id *uuid.UUID
+	err := q.QueryRow(ctx, `
+		select series_id from tasks
+		where project_id = $1 and id = $2 and deleted_at is null
+	`, projectID, taskID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, newAPIError(http.StatusNotFound, "task not found")
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if id == nil {
+		return uuid.Nil, newAPIError(http.StatusNotFound, "task is not recurring")
+	}
+	return *id, nil
+}
+
+// startSeries makes a task the first occurrence, on startsOn, of a new
+// series that uses the task as its template.
+func startSeries(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID, rule recurrence, startsOn time.Time) (uuid.UUID, error) {
+	if _, ok := rule.nth(startsOn, 0); !ok {
+		return uuid.Nil, rruleError("the rule ends before starts_on")
+	}
+	var nextOn *time.Time
+	if d, ok := rule.nth(startsOn, 1); ok {
+		nextOn = &d
+	}
+
+	var seriesID uuid.UUID
+	if err := tx.QueryRow(ctx, `
+		insert into task_series (project_id, rrule, starts_on, next_index, next_on,
+			title, details, assignee_id, difficulty, epic_id, estimate_hours, required_skills, role_key, created_by)
+		select t.project_id, $3, $4, 1, $5,
+			t.title, t.details, t.assignee_id, t.difficulty, t.epic_id, t.estimate_hours, t.required_skills, t.role_key,
+			nullif($6, '')::uuid
+		from tasks t
+		where t.project_id = $1 and t.id = $2
+		returning id
+	`, projectID, taskID, rule.String(), startsOn, nextOn, uid).Scan(&seriesID); err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err := tx.Exec(ctx, `
+		update tasks
+		set series_id = $3,
+			occurrence_on = $4
+		where project_id = $1 and id = $2
+	`, projectID, taskID, seriesID, startsOn)
+	return seriesID, err
+}
+
+// spawnOccurrence creates the series' next occurrence at the end of the
+// first todo column and moves the series on to the one after. With due, it
+// only does so once that date has arrived, and skips dates that were missed
+// (say, while the server was down) so a backlog of copies isn't created. It
+// returns nil when there was nothing to create.
+//
+// Occurrences are created by the system, not a person, so WIP limits don't
+// hold them back.
+func spawnOccurrence(ctx context.Context, tx pgx.Tx, actor string, seriesID uuid.UUID, due bool) (*Task, error) {
+	var (
+		projectID uuid.UUID
+		rrule     string
+		startsOn  time.Time
+		index     int
+		nextOn    *time.Time
+	)
+	err := tx.QueryRow(ctx, `
+		select project_id, rrule, starts_on, next_index, next_on
+		from task_series
+		where id = $1
+		for update
+	`, seriesID).Scan(&projectID, &rrule, &startsOn, &index, &nextOn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if nextOn == nil {
+		return nil, nil
+	}
+	rule, err := parseRRule(rrule)
+	if err != nil {
+		return nil, err
+	}
+
+	on := *nextOn
+	if due {
+		day := today()
+		if on.After(day) {
+			return nil, nil
+		}
+		for {
+			d, ok := rule.nth(startsOn, index+1)
+			if !ok || d.After(day) {
+				break
+			}
+			index, on = index+1, d
+		}
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	status := wf.defaultStatus()
+
+	number, err := nextTaskNumber(ctx, tx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	column := taskRankList(projectID, status)
+	if err := lockRankScope(ctx, tx, column.Scope); err != nil {
+		return nil, err
+	}
+	rank, err := placeRank(ctx, tx, column, nil, rankPlace{})
+	if err != nil {
+		return nil, err
+	}
+
+	var out Task
+	if err := scanTask(tx.QueryRow(ctx, `
+		with inserted as (
+			insert into tasks (project_id, title, details, status, assignee_id, difficulty, rank, epic_id,
+				estimate_hours, required_skills, role_key, number, series_id, occurrence_on, start_date)
+			select s.project_id, s.title, s.details, $2, s.assignee_id, s.difficulty, $3, s.epic_id,
+				s.estimate_hours, s.required_skills, s.role_key, $4, s.id, $5, $5
+			from task_series s
+			where s.id = $1
+			returning *
+		)
+		select `+taskColumns+`
+		from inserted t
+		left join users usr on usr.id = t.assignee_id
+	`, seriesID, status, rank, number, on), &out); err != nil {
+		return nil, err
+	}
+
+	taskID, err := uuid.Parse(out.ID)
+	if err != nil {
+		return nil, err
+	}
+	if out.AssigneeID != nil {
+		if err := watchTask(ctx, tx, taskID, *out.AssigneeID, watchAssigned); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordTaskEvent(ctx, tx, projectID, taskID, actor, taskEventCreated, taskDiff(nil, &out)); err != nil {
+		return nil, err
+	}
+	if _, err := syncMentions(ctx, tx, actor, projectID, taskID, nil, out.Details); err != nil {
+		return nil, err
+	}
+
+	var following *time.Time
+	if d, ok := rule.nth(startsOn, index+1); ok {
+		following = &d
+	}
+	if _, err := tx.Exec(ctx, `
+		update task_series
+		set next_index = $2,
+			next_on = $3
+		where id = $1
+	`, seriesID, index+1, following); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// continueSeries creates the next occurrence early once the last open one
+// is completed.
+func continueSeries(ctx context.Context, tx pgx.Tx, actor string, seriesID uuid.UUID) error {
+	var open bool
+	if err := tx.QueryRow(ctx, `
+		select exists (
+			select 1 from tasks
+			where series_id = $1 and completed_at is null and deleted_at is null
+		)
+		from task_series
+		where id = $1
+		for update
+	`, seriesID).Scan(&open); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if open {
+		return nil
+	}
+	_, err := spawnOccurrence(ctx, tx, actor, seriesID, false)
+	return err
+}
+
+// SpawnDueOccurrences creates an occurrence for every series whose next date
+// has arrived. A failing series is logged and skipped.
+func (h *Handler) SpawnDueOccurrences(ctx context.Context) error {
+	rows, err := h.DB.Query(ctx, `
+		select id from task_series
+		where next_on <= $1
+		order by next_on
+	`, today())
+	if err != nil {
+		return err
+	}
+	var due []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range due {
+		if err := h.spawnDue(ctx, id); err != nil {
+			log.Printf("recurring task series %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) spawnDue(ctx context.Context, seriesID uuid.UUID) error {
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := spawnOccurrence(ctx, tx, "", seriesID, true); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RunRecurrenceScheduler calls SpawnDueOccurrences now and then every
+// interval until ctx is done.
+func (h *Handler) RunRecurrenceScheduler(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if err := h.SpawnDueOccurrences(runCtx); err != nil {
+			log.Printf("recurring tasks: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (h *Handler) GetTaskRecurrence(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	seriesID, err := taskSeriesID(ctx, h.DB, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	s, err := loadSeries(ctx, h.DB, seriesID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// SetTaskRecurrence makes a task repeat. A one-off task becomes the first
+// occurrence of a new series; for a task already in a series the rule is
+// replaced, counting from starts_on (or this occurrence's date), and dates
+// that already have an occurrence are skipped.
+func (h *Handler) SetTaskRecurrence(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req setRecurrenceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	rule, err := parseRRule(req.RRule)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var startsOn *time.Time
+	if req.StartsOn != nil && strings.TrimSpace(*req.StartsOn) != "" {
+		d, err := time.Parse(time.DateOnly, strings.TrimSpace(*req.StartsOn))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid starts_on (want YYYY-MM-DD)"})
+			return
+		}
+		startsOn = &d
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockTaskVersion(ctx, tx, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+	before, err := loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var seriesID uuid.UUID
+	if before.SeriesID == nil {
+		start := today()
+		switch {
+		case startsOn != nil:
+			start = *startsOn
+		case before.StartDate != nil:
+			start, _ = time.Parse(time.DateOnly, *before.StartDate)
+		}
+		seriesID, err = startSeries(ctx, tx, uid, projectID, taskID, rule, start)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		after, err := bumpTaskVersion(ctx, tx, projectID, taskID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if diff := taskDiff(&before, &after); len(diff) > 0 {
+			if err := recordTaskEvent(ctx, tx, projectID, taskID, uid, taskEventUpdated, diff); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+		}
+	} else {
+		seriesID = uuid.MustParse(*before.SeriesID)
+		start := today()
+		switch {
+		case startsOn != nil:
+			start = *startsOn
+		case before.OccurrenceOn != nil:
+			start, _ = time.Parse(time.DateOnly, *before.OccurrenceOn)
+		}
+		if err := reruleSeries(ctx, tx, seriesID, rule, start); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	s, err := loadSeries(ctx, tx, seriesID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
+
+// reruleSeries gives a series a new rule counted from startsOn. The next
+// occurrence is the first one after the series' latest existing occurrence.
+func reruleSeries(ctx context.Context, tx pgx.Tx, seriesID uuid.UUID, rule recurrence, startsOn time.Time) error {
+	if _, ok := rule.nth(startsOn, 0); !ok {
+		return rruleError("the rule ends before starts_on")
+	}
+
+	var latest *time.Time
+	if err := tx.QueryRow(ctx, `
+		select max(occurrence_on) from tasks where series_id = $1
+	`, seriesID).Scan(&latest); err != nil {
+		return err
+	}
+	after := startsOn.AddDate(0, 0, -1)
+	if latest != nil && latest.After(after) {
+		after = *latest
+	}
+
+	index, next, ok := rule.following(startsOn, after)
+	var nextOn *time.Time
+	if ok {
+		nextOn = &next
+	}
+	_, err := tx.Exec(ctx, `
+		update task_series
+		set rrule = $2,
+			starts_on = $3,
+			next_index = $4,
+			next_on = $5,
+			updated_at = now()
+		where id = $1
+	`, seriesID, rule.String(), startsOn, index, nextOn)
+	return err
+}
+
+func bumpTaskVersion(ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID) (Task, error) {
+	if _, err := tx.Exec(ctx, `
+		update tasks set version = version + 1
+		where project_id = $1 and id = $2
+	`, projectID, taskID); err != nil {
+		return Task{}, err
+	}
+	return loadTask(ctx, tx, projectID, taskID)
+}
+
+// StopTaskRecurrence ends a task's series: no more occurrences are created.
+// Existing ones, this task included, are kept.
+func (h *Handler) StopTaskRecurrence(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	seriesID, err := taskSeriesID(ctx, h.DB, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if _, err := h.DB.Exec(ctx, `
+		update task_series
+		set next_on = null,
+			updated_at = now()
+		where id = $1
+	`, seriesID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// UpdateTaskSeries applies a merge patch to the template of the task's
+// series and to each of its open occurrences, with the same rules as
+// UpdateTask. Completed occurrences are left as they were.
+func (h *Handler) UpdateTaskSeries(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var patch seriesPatch
+	if err := decodeMergePatch(c.Request.Body, &patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json", "detail": err.Error()})
+		return
+	}
+	ch, err := patch.change()
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 15*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	seriesID, err := taskSeriesID(ctx, tx, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if ch.AssigneeSet && ch.AssigneeID != nil {
+		if err := checkAssignee(ctx, tx, projectID, *ch.AssigneeID); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+	if ch.EpicSet && ch.EpicID != nil {
+		if err := epicInProject(ctx, tx, projectID, *ch.EpicID); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update task_series
+		set
+			title = coalesce($2, title),
+			details = coalesce($3, details),
+			difficulty = coalesce($4, difficulty),
+			assignee_id = case when $5::boolean then $6::uuid else assignee_id end,
+			epic_id = case when $7::boolean then $8::uuid else epic_id end,
+			estimate_hours = case when $9::boolean then $10::numeric else estimate_hours end,
+			required_skills = coalesce($11::text[], required_skills),
+			role_key = case when $12::boolean then $13 else role_key end,
+			updated_at = now()
+		where id = $1
+	`,
+		seriesID,
+		ch.Title,
+		ch.Details,
+		ch.Difficulty,
+		ch.AssigneeSet,
+		ch.AssigneeID,
+		ch.EpicSet,
+		ch.EpicID,
+		ch.EstimateSet,
+		ch.EstimateHours,
+		ch.RequiredSkills,
+		ch.RoleSet,
+		ch.RoleKey,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rows, err := tx.Query(ctx, `
+		select id from tasks
+		where series_id = $1 and completed_at is null and deleted_at is null
+		order by occurrence_on, created_at
+	`, seriesID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	var open []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		open = append(open, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	tasks := make([]taskWriteResp, 0, len(open))
+	for _, id := range open {
+		out, warnings, err := applyTaskChange(ctx, tx, uid, projectID, id, ch)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		var unresolved []UnresolvedMention
+		if ch.Details != nil {
+			if unresolved, err = syncMentions(ctx, tx, uid, projectID, id, nil, out.Details); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+		}
+		tasks = append(tasks, taskWriteResp{Task: out, Warnings: warnings, UnresolvedMentions: unresolved})
+	}
+
+	s, err := loadSeries(ctx, tx, seriesID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"series": s, "tasks": tasks})
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // canonical form
+	}{
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=10", want: "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+		{rule: " freq=monthly;interval=1 ", want: "FREQ=MONTHLY"},
+		{rule: "FREQ=DAILY;UNTIL=20250301", want: "FREQ=DAILY;UNTIL=20250301"},
+		{rule: "FREQ=DAILY;UNTIL=20250301T235959Z", want: "FREQ=DAILY;UNTIL=20250301"},
+		{rule: "FREQ=WEEKLY;;", want: "FREQ=WEEKLY"},
+	}
+	for _, tt := range tests {
+		r, err := parseRRule(tt.rule)
+		if err != nil {
+			t.Errorf("parseRRule(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("parseRRule(%q).String() = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule   string
+		reason string // "" for the plain "missing rrule" error
+	}{
+		{rule: "  ", reason: ""},
+		{rule: "RRULE:", reason: ""},
+		{rule: "INTERVAL=2", reason: "FREQ is required"},
+		{rule: "FREQ=YEARLY", reason: "FREQ must be DAILY, WEEKLY or MONTHLY"},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", reason: "FREQ given twice"},
+		{rule: "FREQ=DAILY;INTERVAL=0", reason: "INTERVAL must be between 1 and 365"},
+		{rule: "FREQ=DAILY;INTERVAL=366", reason: "INTERVAL must be between 1 and 365"},
+		{rule: "FREQ=DAILY;COUNT=0", reason: "COUNT must be between 1 and 1000"},
+		{rule: "FREQ=DAILY;COUNT=1001", reason: "COUNT must be between 1 and 1000"},
+		{rule: "FREQ=DAILY;UNTIL=2025-03-01", reason: "UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ"},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20250301", reason: "COUNT and UNTIL can't both be set"},
+		{rule: "FREQ=DAILY;BYDAY=MO", reason: "BYDAY is not supported"},
+		{rule: "FREQ", reason: "expected NAME=VALUE, got FREQ"},
+	}
+	for _, tt := range tests {
+		_, err := parseRRule(tt.rule)
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("parseRRule(%q) = %v, want an apiError", tt.rule, err)
+			continue
+		}
+		if reason, _ := apiErr.Body["reason"].(string); reason != tt.reason {
+			t.Errorf("parseRRule(%q) reason = %q, want %q", tt.rule, reason, tt.reason)
+		}
+	}
+}
+
+func TestRecurrenceNth(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		want  []string // occurrences from n = 0 until the rule runs out
+		more  bool     // the rule goes on past want
+	}{
+		{
+			name: "daily", rule: "FREQ=DAILY;INTERVAL=3", start: "2025-02-26",
+			want: []string{"2025-02-26", "2025-03-01", "2025-03-04"}, more: true,
+		},
+		{
+			name: "weekly count", rule: "FREQ=WEEKLY;COUNT=3", start: "2025-12-22",
+			want: []string{"2025-12-22", "2025-12-29", "2026-01-05"},
+		},
+		{
+			name: "monthly clamps to month end", rule: "FREQ=MONTHLY", start: "2025-01-31",
+			want: []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30"}, more: true,
+		},
+		{
+			name: "monthly clamps in leap years", rule: "FREQ=MONTHLY;INTERVAL=12", start: "2024-02-29",
+			want: []string{"2024-02-29", "2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"}, more: true,
+		},
+		{
+			name: "monthly across the year", rule: "FREQ=MONTHLY;INTERVAL=5", start: "2025-10-30",
+			want: []string{"2025-10-30", "2026-03-30", "2026-08-30"}, more: true,
+		},
+		{
+			name: "until is inclusive", rule: "FREQ=DAILY;UNTIL=20250303", start: "2025-03-01",
+			want: []string{"2025-03-01", "2025-03-02", "2025-03-03"},
+		},
+		{
+			name: "until between occurrences", rule: "FREQ=WEEKLY;UNTIL=20250315", start: "2025-03-01",
+			want: []string{"2025-03-01", "2025-03-08", "2025-03-15"},
+		},
+		{
+			name: "until before start", rule: "FREQ=DAILY;UNTIL=20250101", start: "2025-03-01",
+			want: []string{},
+		},
+		{
+			name: "count of one", rule: "FREQ=MONTHLY;COUNT=1", start: "2025-05-31",
+			want: []string{"2025-05-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRRule(%q): %v", tt.rule, err)
+			}
+			start := date(tt.start)
+			for n, w := range tt.want {
+				got, ok := r.nth(start, n)
+				if !ok {
+					t.Fatalf("nth(%d) ran out, want %s", n, w)
+				}
+				if got.Format(time.DateOnly) != w {
+					t.Errorf("nth(%d) = %s, want %s", n, got.Format(time.DateOnly), w)
+				}
+			}
+			if _, ok := r.nth(start, len(tt.want)); ok != tt.more {
+				t.Errorf("nth(%d) ok = %v, want %v", len(tt.want), ok, tt.more)
+			}
+		})
+	}
+}
+
+func TestRecurrenceFollowing(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		day   string
+		n     int
+		want  string
+		ok    bool
+	}{
+		{name: "before start", rule: "FREQ=DAILY", start: "2025-03-10", day: "2025-03-01", n: 0, want: "2025-03-10", ok: true},
+		{name: "on an occurrence", rule: "FREQ=WEEKLY", start: "2025-03-03", day: "2025-03-10", n: 2, want: "2025-03-17", ok: true},
+		{name: "between occurrences", rule: "FREQ=WEEKLY", start: "2025-03-03", day: "2025-03-12", n: 2, want: "2025-03-17", ok: true},
+		{name: "monthly skips short months", rule: "FREQ=MONTHLY", start: "2025-01-31", day: "2025-02-28", n: 2, want: "2025-03-31", ok: true},
+		{name: "count exhausted", rule: "FREQ=DAILY;COUNT=2", start: "2025-03-01", day: "2025-03-05", n: 2, ok: false},
+		{name: "until exhausted", rule: "FREQ=DAILY;UNTIL=20250302", start: "2025-03-01", day: "2025-03-02", n: 2, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRRule(%q): %v", tt.rule, err)
+			}
+			n, d, ok := r.following(date(tt.start), date(tt.day))
+			if n != tt.n || ok != tt.ok {
+				t.Fatalf("following(%s) = %d, %v; want %d, %v", tt.day, n, ok, tt.n, tt.ok)
+			}
+			if ok && d.Format(time.DateOnly) != tt.want {
+				t.Errorf("following(%s) = %s, want %s", tt.day, d.Format(time.DateOnly), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Roadmap DTOs (responses) =========
+type Roadmap struct {
+	ProjectID        string        `json:"project_id"`
+	ProjectName      string        `json:"project_name"`
+	AsOf             string        `json:"as_of"`
+	StartDate        *string       `json:"start_date"`
+	EndDate          *string       `json:"end_date"`
+	Epics            []RoadmapEpic `json:"epics"`
+	UnscheduledTasks int           `json:"unscheduled_tasks"`
+}
+
+type RoadmapEpic struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	StartDate   string   `json:"start_date"`
+	EndDate     string   `json:"end_date"`
+	DependsOn   []string `json:"depends_on"`
+
+	TaskCount int `json:"task_count"`
+	// DoneCount counts tasks in any done-category workflow column.
+	DoneCount int `json:"done_count"`
+	// Progress is weighted by task difficulty, 0..1.
+	Progress        float64 `json:"progress"`
+	PlannedProgress float64 `json:"planned_progress"`
+
+	// ActualEndDate is set once every task is done; ProjectedEndDate
+	// extrapolates the current burn rate while work is still open.
+	ActualEndDate    *string `json:"actual_end_date"`
+	ProjectedEndDate *string `json:"projected_end_date"`
+	// SlippageDays is positive when late, negative when early.
+	SlippageDays int `json:"slippage_days"`
+
+	// Status is one of notStarted | onTrack | atRisk | late | done.
+	Status string `json:"status"`
+	// DependencyConflicts lists dependencies that finish on or after this
+	// epic's start date.
+	DependencyConflicts []string `json:"dependency_conflicts"`
+}
+
+// roadmapEpicRow is the raw per-epic aggregate read from the database.
+type roadmapEpicRow struct {
+	Epic
+	start, end   time.Time
+	taskCount    int
+	doneCount    int
+	points       int
+	donePoints   int
+	lastDoneDate *time.Time
+}
+
+// atRiskMargin is how far actual progress may trail planned progress before
+// an epic is flagged.
+const atRiskMargin = 0.1
+
+func (h *Handler) GetRoadmap(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "json")))
+	if format != "json" && format != "mermaid" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	out := Roadmap{ProjectID: projectID.String(), Epics: []RoadmapEpic{}}
+	if err := h.DB.QueryRow(ctx, `
+		select p.name,
+			(select count(*) from tasks t
+				where t.project_id = p.id and t.epic_id is null and t.deleted_at is null)
+		from projects p
+		where p.id = $1
+	`, projectID).Scan(&out.ProjectName, &out.UnscheduledTasks); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+epicColumns+`,
+			count(t.id),
+			count(t.completed_at),
+			coalesce(sum(t.difficulty), 0),
+			coalesce(sum(t.difficulty) filter (where t.completed_at is not null), 0),
+			max(t.completed_at)
+		from epics e
+		left join tasks t on t.epic_id = e.id and t.deleted_at is null
+		where e.project_id = $1
+		group by e.id
+		order by e.start_date asc, e.sort_index asc, e.created_at asc
+	`, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	epics := make([]roadmapEpicRow, 0)
+	for rows.Next() {
+		var r roadmapEpicRow
+		var createdAt time.Time
+		if err := rows.Scan(
+			&r.ID,
+			&r.ProjectID,
+			&r.Title,
+			&r.Description,
+			&r.start,
+			&r.end,
+			&r.SortIndex,
+			&createdAt,
+			&r.DependsOn,
+			&r.taskCount,
+			&r.doneCount,
+			&r.points,
+			&r.donePoints,
+			&r.lastDoneDate,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		r.StartDate = r.start.Format(time.DateOnly)
+		r.EndDate = r.end.Format(time.DateOnly)
+		r.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		epics = append(epics, r)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	out.AsOf = today.Format(time.DateOnly)
+	out.Epics = buildRoadmap(epics, today)
+	if len(epics) > 0 {
+		first, last := epics[0].start, epics[0].end
+		for _, e := range epics[1:] {
+			if e.start.Before(first) {
+				first = e.start
+			}
+			if e.end.After(last) {
+				last = e.end
+			}
+		}
+		s, e := first.Format(time.DateOnly), last.Format(time.DateOnly)
+		out.StartDate, out.EndDate = &s, &e
+	}
+
+	switch format {
+	case "mermaid":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(renderMermaidGantt(out)))
+	case "csv":
+		b, err := renderRoadmapCSV(out)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="roadmap.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", b)
+	default:
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// buildRoadmap turns the per-epic aggregates into timeline entries as of today.
+func buildRoadmap(rows []roadmapEpicRow, today time.Time) []RoadmapEpic {
+	ends := make(map[string]time.Time, len(rows))
+	out := make([]RoadmapEpic, 0, len(rows))
+
+	for _, r := range rows {
+		e := RoadmapEpic{
+			ID:                  r.ID,
+			Title:               r.Title,
+			Description:         r.Description,
+			StartDate:           r.StartDate,
+			EndDate:             r.EndDate,
+			DependsOn:           r.DependsOn,
+			TaskCount:           r.taskCount,
+			DoneCount:           r.doneCount,
+			DependencyConflicts: []string{},
+		}
+		if e.DependsOn == nil {
+			e.DependsOn = []string{}
+		}
+
+		if r.points > 0 {
+			e.Progress = round2(float64(r.donePoints) / float64(r.points))
+		}
+
+		duration := epicDays(r.start, r.end)
+		elapsed := daysBetween(r.start, today) + 1
+		switch {
+		case elapsed <= 0:
+			e.PlannedProgress = 0
+		case elapsed >= duration:
+			e.PlannedProgress = 1
+		default:
+			e.PlannedProgress = round2(float64(elapsed) / float64(duration))
+		}
+
+		finish := r.end
+		switch {
+		case r.taskCount > 0 && r.doneCount == r.taskCount:
+			actual := r.lastDoneDate.UTC().Truncate(24 * time.Hour)
+			s := actual.Format(time.DateOnly)
+			e.ActualEndDate = &s
+			e.SlippageDays = daysBetween(r.end, actual)
+			e.Status = "done"
+			finish = actual
+
+		case today.After(r.end):
+			e.SlippageDays = daysBetween(r.end, today)
+			e.Status = "late"
+			finish = today
+
+		case today.Before(r.start) && r.doneCount == 0:
+			e.Status = "notStarted"
+
+		default:
+			// Extrapolate the burn rate so far to estimate the finish date.
+			if e.Progress > 0 && elapsed > 0 {
+				need := int(math.Ceil(float64(elapsed) / e.Progress))
+				projected := r.start.AddDate(0, 0, need-1)
+				s := projected.Format(time.DateOnly)
+				e.ProjectedEndDate = &s
+				e.SlippageDays = daysBetween(r.end, projected)
+				finish = projected
+			}
+			if e.Progress+atRiskMargin < e.PlannedProgress || e.SlippageDays > 0 {
+				e.Status = "atRisk"
+			} else {
+				e.Status = "onTrack"
+			}
+		}
+
+		ends[r.ID] = finish
+		out = append(out, e)
+	}
+
+	startByID := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		startByID[r.ID] = r.start
+	}
+	for i := range out {
+		for _, dep := range out[i].DependsOn {
+			end, ok := ends[dep]
+			if ok && !end.Before(startByID[out[i].ID]) {
+				out[i].DependencyConflicts = append(out[i].DependencyConflicts, dep)
+			}
+		}
+	}
+
+	return out
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// epicDays is an epic's length with both its start and end dates counted.
+func epicDays(start, end time.Time) int {
+	return daysBetween(start, end) + 1
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// renderMermaidGantt emits a Mermaid gantt chart with one bar per epic.
+// Each epic's dependencies are noted in a comment above its bar.
+func renderMermaidGantt(r Roadmap) string {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s\n", mermaidText(r.ProjectName))
+	b.WriteString("    dateFormat YYYY-MM-DD\n")
+	b.WriteString("    section Epics\n")
+
+	ids := make(map[string]string, len(r.Epics))
+	for i, e := range r.Epics {
+		ids[e.ID] = "e" + strconv.Itoa(i+1)
+	}
+
+	for _, e := range r.Epics {
+		tags := make([]string, 0, 2)
+		switch e.Status {
+		case "done":
+			tags = append(tags, "done")
+		case "late":
+			tags = append(tags, "crit", "active")
+		case "onTrack":
+			tags = append(tags, "active")
+		case "atRisk":
+			tags = append(tags, "crit")
+		}
+		tags = append(tags, ids[e.ID])
+
+		// Bars are given as a length rather than an end date: Mermaid's end
+		// dates are exclusive, while an epic's end date is its last day.
+		// A dependency that ends too late pushes the bar: it starts after
+		// the dependency and keeps its planned length. Met dependencies keep
+		// the planned dates, as "after" would pull the bar earlier.
+		start, _ := time.Parse(time.DateOnly, e.StartDate)
+		end, _ := time.Parse(time.DateOnly, e.EndDate)
+		length := strconv.Itoa(epicDays(start, end)) + "d"
+		var after []string
+		for _, d := range e.DependencyConflicts {
+			if id, ok := ids[d]; ok {
+				after = append(after, id)
+			}
+		}
+		if len(after) > 0 {
+			tags = append(tags, "after "+strings.Join(after, " "), length)
+		} else {
+			tags = append(tags, e.StartDate, length)
+		}
+
+		var deps []string
+		for _, d := range e.DependsOn {
+			if id, ok := ids[d]; ok {
+				deps = append(deps, id)
+			}
+		}
+		if len(deps) > 0 {
+			fmt.Fprintf(&b, "    %%%% %s depends on %s\n", ids[e.ID], strings.Join(deps, ", "))
+		}
+		fmt.Fprintf(&b, "    %s :%s\n", mermaidText(e.Title), strings.Join(tags, ", "))
+	}
+
+	return b.String()
+}
+
+// mermaidText strips characters that end a gantt task name or title.
+func mermaidText(s string) string {
+	return strings.NewReplacer(":", " ", ";", " ", "#", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+func renderRoadmapCSV(r Roadmap) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	titles := make(map[string]string, len(r.Epics))
+	for _, e := range r.Epics {
+		titles[e.ID] = e.Title
+	}
+
+	if err := w.Write([]string{
+		"id", "title", "start_date", "end_date", "depends_on",
+		"tasks", "done", "progress", "planned_progress",
+		"actual_end_date", "projected_end_date", "slippage_days", "status",
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, e := range r.Epics {
+		deps := make([]string, 0, len(e.DependsOn))
+		for _, d := range e.DependsOn {
+			deps = append(deps, titles[d])
+		}
+		if err := w.Write([]string{
+			e.ID,
+			e.Title,
+			e.StartDate,
+			e.EndDate,
+			strings.Join(deps, "; "),
+			strconv.Itoa(e.TaskCount),
+			strconv.Itoa(e.DoneCount),
+			strconv.FormatFloat(e.Progress, 'f', 2, 64),
+			strconv.FormatFloat(e.PlannedProgress, 'f', 2, 64),
+			derefString(e.ActualEndDate),
+			derefString(e.ProjectedEndDate),
+			strconv.Itoa(e.SlippageDays),
+			e.Status,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
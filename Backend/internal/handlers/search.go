@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 25
+	maxSearchLimit     = 100
+	maxSearchQueryLen  = 500
+)
+
+// ========= Search DTOs (responses) =========
+type SearchHit struct {
+	MyTask
+	Rank float64 `json:"rank"`
+}
+
+type SearchPage struct {
+	Tasks  []SearchHit `json:"tasks"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// queryError is a syntax error in a search query. Pos is the byte offset of
+// the offending token so clients can underline it.
+type queryError struct {
+	Pos    int    `json:"position"`
+	Token  string `json:"token"`
+	Reason string `json:"reason"`
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("at %d (%q): %s", e.Pos, e.Token, e.Reason)
+}
+
+// searchQuery is a parsed query. Repeating a field ORs its values; different
+// fields AND together, as does free text.
+//
+//	status:blocked assignee:@alice difficulty>=4 project:"Mobile App" login bug
+type searchQuery struct {
+	Statuses  []string
+	Assignees []string // lower-cased usernames
+	Me        bool     // assignee:me
+	Unowned   bool     // assignee:none
+	Projects  []string // names or keys, lower-cased
+	MinDiff   int
+	MaxDiff   int
+	Text      string // websearch_to_tsquery syntax
+}
+
+type searchToken struct {
+	Pos int
+	Raw string
+}
+
+// tokenizeSearch splits on whitespace, keeping double-quoted runs (which may
+// contain spaces) inside their token.
+func tokenizeSearch(q string) ([]searchToken, error) {
+	var out []searchToken
+	i := 0
+	for i < len(q) {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		start := i
+		quoted := false
+		for i < len(q) {
+			r, size := utf8.DecodeRuneInString(q[i:])
+			if r == '"' {
+				quoted = !quoted
+			} else if !quoted && unicode.IsSpace(r) {
+				break
+			}
+			i += size
+		}
+		if quoted {
+			return nil, &queryError{Pos: start, Token: q[start:i], Reason: "unterminated quote"}
+		}
+		out = append(out, searchToken{Pos: start, Raw: q[start:i]})
+	}
+	return out, nil
+}
+
+// unquote strips one pair of surrounding double quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// splitFilter breaks "field<op>value" apart. ok is false for plain words.
+func splitFilter(raw string) (field, op, value string, ok bool) {
+	i := 0
+	for i < len(raw) && raw[i] >= 'a' && raw[i] <= 'z' {
+		i++
+	}
+	if i == 0 || i == len(raw) {
+		return "", "", "", false
+	}
+	for _, o := range []string{">=", "<=", ":", "=", ">", "<"} {
+		if strings.HasPrefix(raw[i:], o) {
+			return raw[:i], o, raw[i+len(o):], true
+		}
+	}
+	return "", "", "", false
+}
+
+func parseSearchQuery(q string) (searchQuery, error) {
+	// Empty rather than nil slices: they are bound as arrays, and a nil one
+	// would arrive as null.
+	sq := searchQuery{
+		Statuses:  []string{},
+		Assignees: []string{},
+		Projects:  []string{},
+		MinDiff:   1,
+		MaxDiff:   5,
+	}
+
+	tokens, err := tokenizeSearch(q)
+	if err != nil {
+		return sq, err
+	}
+
+	var text []string
+	for _, tok := range tokens {
+		field, op, value, ok := splitFilter(tok.Raw)
+		if !ok {
+			text = append(text, tok.Raw)
+			continue
+		}
+
+		fail := func(reason string) error {
+			return &queryError{Pos: tok.Pos, Token: tok.Raw, Reason: reason}
+		}
+		value = strings.TrimSpace(unquote(value))
+		if value == "" {
+			return sq, fail("missing value")
+		}
+		if field != "difficulty" && op != ":" {
+			return sq, fail(fmt.Sprintf("%s only supports ':'", field))
+		}
+
+		switch field {
+		case "status":
+			sq.Statuses = append(sq.Statuses, value)
+
+		case "assignee":
+			switch v := strings.ToLower(value); {
+			case v == "me":
+				sq.Me = true
+			case v == "none":
+				sq.Unowned = true
+			case strings.HasPrefix(v, "@") && len(v) > 1:
+				sq.Assignees = append(sq.Assignees, v[1:])
+			default:
+				return sq, fail("assignee must be @username, me or none")
+			}
+
+		case "project":
+			sq.Projects = append(sq.Projects, strings.ToLower(value))
+
+		case "difficulty":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 5 {
+				return sq, fail("difficulty must be 1-5")
+			}
+			switch op {
+			case ":", "=":
+				sq.MinDiff, sq.MaxDiff = max(sq.MinDiff, n), min(sq.MaxDiff, n)
+			case ">=":
+				sq.MinDiff = max(sq.MinDiff, n)
+			case ">":
+				sq.MinDiff = max(sq.MinDiff, n+1)
+			case "<=":
+				sq.MaxDiff = min(sq.MaxDiff, n)
+			case "<":
+				sq.MaxDiff = min(sq.MaxDiff, n-1)
+			}
+
+		default:
+			return sq, fail("unknown field (quote it to search for the text)")
+		}
+	}
+
+	sq.Text = strings.Join(text, " ")
+	return sq, nil
+}
+
+// searchMatches follows "from tasks t" in the count and page queries. $1 is
+// the caller.
+const searchMatches = `
+	join projects p on p.id = t.project_id
+	join projects_members pm on pm.project_id = t.project_id and pm.user_id = $1::uuid
+	left join users usr on usr.id = t.assignee_id
+	cross join (select websearch_to_tsquery('english', $2) as tsq) q
+	where t.deleted_at is null
+		and ($2 = '' or t.search @@ q.tsq)
+		and (cardinality($3::text[]) = 0 or t.status = any($3))
+		and (
+			(cardinality($4::text[]) = 0 and not $5 and not $6)
+			or lower(usr.username) = any($4)
+			or ($5 and t.assignee_id = $1::uuid)
+			or ($6 and t.assignee_id is null)
+		)
+		and (cardinality($7::text[]) = 0 or lower(p.name) = any($7) or lower(p.key) = any($7))
+		and t.difficulty between $8 and $9
+`
+
+// SearchTasks runs a query across every project the caller is a member of.
+// Free text is matched with Postgres full-text search over title and
+// details and ranks the results; without it the newest tasks come first.
+//
+// Query params:
+//   - q: the query, e.g. status:blocked assignee:@alice difficulty>=4 login
+//   - limit, offset: paging
+func (h *Handler) SearchTasks(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	raw := strings.TrimSpace(c.Query("q"))
+	if len(raw) > maxSearchQueryLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query too long", "max": maxSearchQueryLen})
+		return
+	}
+	sq, err := parseSearchQuery(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "detail": err})
+		return
+	}
+
+	limit, ok := queryInt(c, "limit", defaultSearchLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, ok := queryInt(c, "offset", 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	args := []any{uid, sq.Text, sq.Statuses, sq.Assignees, sq.Me, sq.Unowned, sq.Projects, sq.MinDiff, sq.MaxDiff}
+	page := SearchPage{Tasks: []SearchHit{}, Limit: limit, Offset: offset}
+
+	if err := h.DB.QueryRow(ctx, `select count(*) from tasks t `+searchMatches, args...).Scan(&page.Total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if page.Total == 0 || offset >= page.Total {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+taskColumnsAt("pos.sort_index")+`, p.name,
+			case when $2 = '' then 0 else ts_rank_cd(t.search, q.tsq) end::float8 as search_rank
+		from tasks t `+memberTaskPositions("$1")+`
+		`+searchMatches+`
+		order by search_rank desc, t.created_at desc, t.id
+		limit $10 offset $11
+	`, append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		if err := scanTask(scanAlso(rows, &hit.ProjectName, &hit.Rank), &hit.Task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		page.Tasks = append(page.Tasks, hit)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeSearch(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want []searchToken
+	}{
+		{name: "empty", q: "", want: nil},
+		{name: "spaces only", q: "   ", want: nil},
+		{name: "words", q: "login  bug", want: []searchToken{{0, "login"}, {7, "bug"}}},
+		{name: "quoted run", q: `project:"Mobile App" crash`, want: []searchToken{{0, `project:"Mobile App"`}, {21, "crash"}}},
+		{name: "quoted phrase", q: `"two words"`, want: []searchToken{{0, `"two words"`}}},
+		{name: "leading space", q: "  x", want: []searchToken{{2, "x"}}},
+		{name: "multibyte", q: "café bug", want: []searchToken{{0, "café"}, {6, "bug"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenizeSearch(tt.q)
+			if err != nil {
+				t.Fatalf("tokenizeSearch(%q): %v", tt.q, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeSearch(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	base := func(edit func(*searchQuery)) searchQuery {
+		sq := searchQuery{
+			Statuses:  []string{},
+			Assignees: []string{},
+			Projects:  []string{},
+			MinDiff:   1,
+			MaxDiff:   5,
+		}
+		edit(&sq)
+		return sq
+	}
+	tests := []struct {
+		name string
+		q    string
+		want searchQuery
+	}{
+		{name: "empty", q: "", want: base(func(sq *searchQuery) {})},
+		{name: "free text", q: "login bug", want: base(func(sq *searchQuery) { sq.Text = "login bug" })},
+		{
+			name: "repeated status ORs",
+			q:    "status:todo status:blocked",
+			want: base(func(sq *searchQuery) { sq.Statuses = []string{"todo", "blocked"} }),
+		},
+		{
+			name: "assignees",
+			q:    "assignee:@Alice assignee:me assignee:none",
+			want: base(func(sq *searchQuery) {
+				sq.Assignees = []string{"alice"}
+				sq.Me = true
+				sq.Unowned = true
+			}),
+		},
+		{
+			name: "quoted project",
+			q:    `project:"Mobile App" crash`,
+			want: base(func(sq *searchQuery) {
+				sq.Projects = []string{"mobile app"}
+				sq.Text = "crash"
+			}),
+		},
+		{name: "difficulty exact", q: "difficulty:3", want: base(func(sq *searchQuery) { sq.MinDiff, sq.MaxDiff = 3, 3 })},
+		{name: "difficulty equals", q: "difficulty=2", want: base(func(sq *searchQuery) { sq.MinDiff, sq.MaxDiff = 2, 2 })},
+		{name: "difficulty at least", q: "difficulty>=4", want: base(func(sq *searchQuery) { sq.MinDiff = 4 })},
+		{name: "difficulty above", q: "difficulty>3", want: base(func(sq *searchQuery) { sq.MinDiff = 4 })},
+		{name: "difficulty at most", q: "difficulty<=2", want: base(func(sq *searchQuery) { sq.MaxDiff = 2 })},
+		{name: "difficulty below", q: "difficulty<2", want: base(func(sq *searchQuery) { sq.MaxDiff = 1 })},
+		{
+			name: "difficulty range intersects",
+			q:    "difficulty>=2 difficulty<5 difficulty>1",
+			want: base(func(sq *searchQuery) { sq.MinDiff, sq.MaxDiff = 2, 4 }),
+		},
+		{
+			name: "empty range is not an error",
+			q:    "difficulty>4 difficulty<2",
+			want: base(func(sq *searchQuery) { sq.MinDiff, sq.MaxDiff = 5, 1 }),
+		},
+		{name: "quoted filter is text", q: `"status:done"`, want: base(func(sq *searchQuery) { sq.Text = `"status:done"` })},
+		{name: "upper-case word is text", q: "URL:x", want: base(func(sq *searchQuery) { sq.Text = "URL:x" })},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSearchQuery(tt.q)
+			if err != nil {
+				t.Fatalf("parseSearchQuery(%q): %v", tt.q, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSearchQuery(%q) = %+v, want %+v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want queryError
+	}{
+		{name: "unterminated quote", q: `bug project:"Mobile`, want: queryError{Pos: 4, Token: `project:"Mobile`, Reason: "unterminated quote"}},
+		{name: "unknown field", q: "a color:red", want: queryError{Pos: 2, Token: "color:red", Reason: "unknown field (quote it to search for the text)"}},
+		{name: "missing value", q: `status:""`, want: queryError{Pos: 0, Token: `status:""`, Reason: "missing value"}},
+		{name: "operator on status", q: "status>=x", want: queryError{Pos: 0, Token: "status>=x", Reason: "status only supports ':'"}},
+		{name: "difficulty not a number", q: "difficulty:hard", want: queryError{Pos: 0, Token: "difficulty:hard", Reason: "difficulty must be 1-5"}},
+		{name: "difficulty out of range", q: "x  difficulty>=6", want: queryError{Pos: 3, Token: "difficulty>=6", Reason: "difficulty must be 1-5"}},
+		{name: "bare at", q: "assignee:@", want: queryError{Pos: 0, Token: "assignee:@", Reason: "assignee must be @username, me or none"}},
+		{name: "assignee without at", q: "assignee:alice", want: queryError{Pos: 0, Token: "assignee:alice", Reason: "assignee must be @username, me or none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSearchQuery(tt.q)
+			var qe *queryError
+			if !errors.As(err, &qe) {
+				t.Fatalf("parseSearchQuery(%q) = %v, want a queryError", tt.q, err)
+			}
+			if *qe != tt.want {
+				t.Errorf("parseSearchQuery(%q) = %+v, want %+v", tt.q, *qe, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxRequiredSkills = 20
+	maxSkillName      = 50
+
+	// Points each factor can add to a candidate's score (out of 100).
+	skillPoints    = 60
+	workloadPoints = 30
+	rolePoints     = 10
+
+	// Open difficulty at which a member gets no workload points at all.
+	workloadCap = 20
+)
+
+// ========= Suggestion DTOs (responses) =========
+type ScoreFactor struct {
+	Factor    string  `json:"factor"` // skills | workload | role
+	Points    float64 `json:"points"`
+	MaxPoints float64 `json:"max_points"`
+	Detail    string  `json:"detail"`
+}
+
+type AssigneeSuggestion struct {
+	UserID    string        `json:"user_id"`
+	Username  string        `json:"username"`
+	RoleKey   string        `json:"role_key"`
+	Score     float64       `json:"score"`
+	OpenTasks int           `json:"open_tasks"`
+	OpenLoad  int           `json:"open_load"` // summed difficulty of open tasks
+	Breakdown []ScoreFactor `json:"breakdown"`
+}
+
+// normalizeSkills trims and de-duplicates (case-insensitively) a task's
+// required skills.
+func normalizeSkills(in []string) ([]string, error) {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, s := range in {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if len([]rune(s)) > maxSkillName {
+			return nil, newAPIError(http.StatusBadRequest, "skill name too long")
+		}
+		if seen[strings.ToLower(s)] {
+			continue
+		}
+		seen[strings.ToLower(s)] = true
+		out = append(out, s)
+	}
+	if len(out) > maxRequiredSkills {
+		return nil, newAPIError(http.StatusBadRequest, "too many required skills")
+	}
+	return out, nil
+}
+
+// normalizeRoleKey trims a task's wanted role; blank clears it.
+func normalizeRoleKey(s string) (*string, error) {
+	r := strings.TrimSpace(s)
+	if r == "" {
+		return nil, nil
+	}
+	if len([]rune(r)) > maxSkillName {
+		return nil, newAPIError(http.StatusBadRequest, "role_key too long")
+	}
+	return &r, nil
+}
+
+// suggestAssignees scores every project member for work needing skills and
+// role (either may be empty), best first. exclude keeps a task out of the
+// workload count, e.g. the one being assigned.
+//
+// A member earns up to skillPoints for their average proficiency across the
+// required skills, up to workloadPoints for having little open work (summed
+// difficulty across all their projects) and rolePoints when their role
+// matches.
+func suggestAssignees(ctx context.Context, q dbtx, projectID uuid.UUID, skills []string, role *string, exclude *uuid.UUID) ([]AssigneeSuggestion, error) {
+	lowered := make([]string, len(skills))
+	for i, s := range skills {
+		lowered[i] = strings.ToLower(s)
+	}
+	excludeID := uuid.Nil
+	if exclude != nil {
+		excludeID = *exclude
+	}
+
+	rows, err := q.Query(ctx, `
+		select
+			pm.user_id::text,
+			pm.username,
+			pm.roleKey,
+			coalesce((
+				select count(*)
+				from tasks t
+				where t.assignee_id = pm.user_id and t.completed_at is null
+					and t.deleted_at is null and t.id <> $3
+			), 0)::int,
+			coalesce((
+				select sum(t.difficulty)
+				from tasks t
+				where t.assignee_id = pm.user_id and t.completed_at is null
+					and t.deleted_at is null and t.id <> $3
+			), 0)::int,
+			array(
+				select coalesce(max(s.proficiency), 0)
+				from unnest($2::text[]) with ordinality as r(name, i)
+				left join skills s on s.user_id = pm.user_id and lower(s.name) = r.name
+				group by r.i
+				order by r.i
+			)
+		from projects_members pm
+		where pm.project_id = $1
+	`, projectID, lowered, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]AssigneeSuggestion, 0)
+	for rows.Next() {
+		var s AssigneeSuggestion
+		var prof []int
+		if err := rows.Scan(&s.UserID, &s.Username, &s.RoleKey, &s.OpenTasks, &s.OpenLoad, &prof); err != nil {
+			return nil, err
+		}
+		s.Breakdown = []ScoreFactor{
+			skillFactor(skills, prof),
+			workloadFactor(s.OpenTasks, s.OpenLoad),
+			roleFactor(role, s.RoleKey),
+		}
+		for _, f := range s.Breakdown {
+			s.Score += f.Points
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		if out[i].OpenLoad != out[j].OpenLoad {
+			return out[i].OpenLoad < out[j].OpenLoad
+		}
+		return strings.ToLower(out[i].Username) < strings.ToLower(out[j].Username)
+	})
+	return out, nil
+}
+
+func round1(f float64) float64 {
+	return float64(int(f*10+0.5)) / 10
+}
+
+func skillFactor(skills []string, prof []int) ScoreFactor {
+	f := ScoreFactor{Factor: "skills", MaxPoints: skillPoints}
+	if len(skills) == 0 {
+		f.Detail = "no skills required"
+		return f
+	}
+	parts := make([]string, len(skills))
+	total := 0
+	for i, name := range skills {
+		p := 0
+		if i < len(prof) {
+			p = prof[i]
+		}
+		total += p
+		if p == 0 {
+			parts[i] = name + " missing"
+		} else {
+			parts[i] = fmt.Sprintf("%s %d/10", name, p)
+		}
+	}
+	f.Points = round1(skillPoints * float64(total) / float64(10*len(skills)))
+	f.Detail = strings.Join(parts, ", ")
+	return f
+}
+
+func workloadFactor(tasks, load int) ScoreFactor {
+	f := ScoreFactor{Factor: "workload", MaxPoints: workloadPoints}
+	free := 1 - float64(min(load, workloadCap))/workloadCap
+	f.Points = round1(workloadPoints * free)
+	f.Detail = fmt.Sprintf("%d open tasks, difficulty %d", tasks, load)
+	return f
+}
+
+func roleFactor(want *string, have string) ScoreFactor {
+	f := ScoreFactor{Factor: "role", MaxPoints: rolePoints}
+	switch {
+	case want == nil:
+		f.Detail = "no role set on the task"
+	case strings.EqualFold(*want, have):
+		f.Points = rolePoints
+		f.Detail = "role matches (" + have + ")"
+	default:
+		f.Detail = fmt.Sprintf("role %s, task wants %s", have, *want)
+	}
+	return f
+}
+
+// SuggestAssignees ranks the project's members for a task by the task's
+// required skills, their open workload and their role, explaining each
+// score. It changes nothing; assign the pick with UpdateTask.
+func (h *Handler) SuggestAssignees(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	task, err := loadTask(ctx, h.DB, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	candidates, err := suggestAssignees(ctx, h.DB, projectID, task.RequiredSkills, task.RoleKey, &taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":         task.ID,
+		"required_skills": task.RequiredSkills,
+		"role_key":        task.RoleKey,
+		"candidates":      candidates,
+	})
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ========= Tag DTOs (responses) =========
+type ProjectTag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type TagWithCount struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Color        string `json:"color"`
+	ProjectCount int    `json:"project_count"`
+}
+
+// ========= Requests =========
+type createTagReq struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type updateTagReq struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+const maxTagName = 32
+
+var tagColorRe = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+func normalizeTagName(s string) (string, bool) {
+	name := strings.TrimSpace(s)
+	return name, name != "" && len([]rune(name)) <= maxTagName
+}
+
+func validTagColor(s string) bool {
+	return s == "" || tagColorRe.MatchString(s)
+}
+
+// parseTagFilter reads ?tag=a&tag=b or ?tag=a,b into lowercased, deduped names.
+func parseTagFilter(c *gin.Context) []string {
+	out := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, raw := range c.QueryArray("tag") {
+		for _, part := range strings.Split(raw, ",") {
+			name := strings.ToLower(strings.TrimSpace(part))
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (h *Handler) ListTags(c *gin.Context) {
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	// Only count projects the user can still see.
+	rows, err := h.DB.Query(ctx, `
+		select
+			tg.id::text,
+			tg.name,
+			tg.color,
+			count(pm.project_id)
+		from tags tg
+		left join project_tags pt on pt.tag_id = tg.id
+		left join projects_members pm on pm.project_id = pt.project_id and pm.user_id = tg.user_id
+		where tg.user_id = $1::uuid
+		group by tg.id
+		order by lower(tg.name) asc
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	out := make([]TagWithCount, 0)
+	for rows.Next() {
+		var t TagWithCount
+		if err := rows.Scan(&t.ID, &t.Name, &t.Color, &t.ProjectCount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) CreateTag(c *gin.Context) {
+	var req createTagReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	name, ok := normalizeTagName(req.Name)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid name"})
+		return
+	}
+	color := strings.TrimSpace(req.Color)
+	if !validTagColor(color) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid color"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	var out ProjectTag
+	if err := h.DB.QueryRow(ctx, `
+		insert into tags (user_id, name, color)
+		values ($1::uuid, $2, $3)
+		returning id::text, name, color
+	`, userID, name, color).Scan(&out.ID, &out.Name, &out.Color); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) UpdateTag(c *gin.Context) {
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	tagID, ok := parseUUIDParam(c, "tagId", "tag")
+	if !ok {
+		return
+	}
+
+	var req updateTagReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	var name *string
+	if req.Name != nil {
+		n, ok := normalizeTagName(*req.Name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid name"})
+			return
+		}
+		name = &n
+	}
+	var color *string
+	if req.Color != nil {
+		col := strings.TrimSpace(*req.Color)
+		if !validTagColor(col) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid color"})
+			return
+		}
+		color = &col
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	var out ProjectTag
+	if err := h.DB.QueryRow(ctx, `
+		update tags
+		set name = coalesce($3, name),
+			color = coalesce($4, color)
+		where id = $1 and user_id = $2::uuid
+		returning id::text, name, color
+	`, tagID, userID, name, color).Scan(&out.ID, &out.Name, &out.Color); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *Handler) DeleteTag(c *gin.Context) {
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	tagID, ok := parseUUIDParam(c, "tagId", "tag")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	cmd, err := h.DB.Exec(ctx, `
+		delete from tags
+		where id = $1 and user_id = $2::uuid
+	`, tagID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *Handler) TagProject(c *gin.Context) {
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	tagID, ok := parseUUIDParam(c, "tagId", "tag")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, userID) {
+		return
+	}
+
+	cmd, err := h.DB.Exec(ctx, `
+		insert into project_tags (project_id, tag_id)
+		select $1, tg.id
+		from tags tg
+		where tg.id = $2 and tg.user_id = $3::uuid
+		on conflict do nothing
+	`, projectID, tagID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		// Either the tag isn't ours or it's already on the project.
+		var owned bool
+		if err := h.DB.QueryRow(ctx, `
+			select exists (select 1 from tags where id = $1 and user_id = $2::uuid)
+		`, tagID, userID).Scan(&owned); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if !owned {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (h *Handler) UntagProject(c *gin.Context) {
+	userID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	tagID, ok := parseUUIDParam(c, "tagId", "tag")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	cmd, err := h.DB.Exec(ctx, `
+		delete from project_tags pt
+		using tags tg
+		where tg.id = pt.tag_id
+			and pt.project_id = $1
+			and pt.tag_id = $2
+			and tg.user_id = $3::uuid
+	`, projectID, tagID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
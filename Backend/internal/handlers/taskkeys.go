@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// projectKeyRe is the short prefix of a project's task keys, e.g. FRG.
+var projectKeyRe = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+var errProjectKeyTaken = newAPIError(http.StatusConflict, "project key already in use")
+
+// normalizeProjectKey upper-cases a requested key and checks its format.
+func normalizeProjectKey(s string) (string, error) {
+	key := strings.ToUpper(strings.TrimSpace(s))
+	if !projectKeyRe.MatchString(key) {
+		return "", newAPIError(http.StatusBadRequest, "invalid project key (2-10 letters or digits, starting with a letter)")
+	}
+	return key, nil
+}
+
+// deriveProjectKey picks an unused key from the project name: its first three
+// letters or digits, with a number appended when that is taken.
+func deriveProjectKey(ctx context.Context, q dbtx, name string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			if b.Len() == 3 {
+				break
+			}
+		}
+	}
+	base := b.String()
+	if !projectKeyRe.MatchString(base) {
+		base = "PRJ"
+	}
+
+	rows, err := q.Query(ctx, `
+		select key from projects where key ~ ('^' || $1 || '[0-9]*$')
+	`, base)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return "", err
+		}
+		taken[k] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	key := base
+	for n := 2; taken[key]; n++ {
+		key = base + strconv.Itoa(n)
+	}
+	return key, nil
+}
+
+// isProjectKeyConflict reports whether err is the unique index on
+// projects.key, i.e. another project got the key first.
+func isProjectKeyConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_projects_key"
+}
+
+// nextTaskNumber hands out the project's next task number. The row lock on
+// the project makes concurrent inserts take turns, and the counter only ever
+// goes up, so numbers are never reused after deletes. Call it in the same
+// transaction that inserts the task.
+func nextTaskNumber(ctx context.Context, tx pgx.Tx, projectID uuid.UUID) (int, error) {
+	var n int
+	err := tx.QueryRow(ctx, `
+		update projects
+		set next_task_number = next_task_number + 1
+		where id = $1
+		returning next_task_number - 1
+	`, projectID).Scan(&n)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, newAPIError(http.StatusNotFound, "project not found")
+	}
+	return n, err
+}
+
+// parseTaskKey splits "FRG-42" into its project key and number.
+func parseTaskKey(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, '-')
+	if i <= 0 {
+		return "", 0, false
+	}
+	key := strings.ToUpper(s[:i])
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil || n < 1 || !projectKeyRe.MatchString(key) {
+		return "", 0, false
+	}
+	return key, n, true
+}
+
+// GetTaskByKey resolves a key such as FRG-42 among the caller's projects.
+func (h *Handler) GetTaskByKey(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	key, number, ok := parseTaskKey(strings.TrimSpace(c.Param("taskKey")))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task key"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	// Non-members get the same 404 as a missing task, so keys can't be
+	// probed for projects the caller isn't on.
+	var out MyTask
+	err := scanTask(scanAlso(h.DB.QueryRow(ctx, `
+		select `+taskColumns+`, p.name
+		from tasks t
+		join projects p on p.id = t.project_id
+		join projects_members pm on pm.project_id = p.id and pm.user_id = $3::uuid
+		left join users usr on usr.id = t.assignee_id
+		where p.key = $1 and t.number = $2 and t.deleted_at is null
+	`, key, number, uid), &out.ProjectName), &out.Task)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, out)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -13,15 +16,47 @@ import (
 // ========= Task DTOs (responses) =========
 type Task struct {
 	ID string					`json:"id"`
+	Key string					`json:"key"` // e.g. FRG-42
+	Number int					`json:"number"`
 	ProjectID string			`json:"project_id"`
+	EpicID *string				`json:"epic_id"`
 	Title string				`json:"title"`
 	Details string				`json:"details"`
 	Status string				`json:"status"`
 	AssigneeID *string			`json:"assignee_id"`
 	AssigneeUsername *string	`json:"assignee_username"`
+	StatusReason *string		`json:"status_reason"`
 	Difficulty int				`json:"difficulty"`
 	SortIndex int				`json:"sort_index"`
 	CreatedAt string			`json:"created_at"`
+	CompletedAt *string			`json:"completed_at"`
+	StartDate *string			`json:"start_date"` // YYYY-MM-DD
+	DueAt *string				`json:"due_at"`
+	CommentCount int			`json:"comment_count"`
+
+	// Overdue is set for open tasks whose due_at has passed.
+	Overdue bool `json:"overdue"`
+	// EstimateHours is the original estimate; LoggedHours sums the worklogs.
+	EstimateHours *float64 `json:"estimate_hours"`
+	LoggedHours   float64  `json:"logged_hours"`
+	// BlockedBy lists the ids of tasks that block this one and are still open.
+	BlockedBy []string `json:"blocked_by"`
+	// ChecklistDone of ChecklistTotal items are checked, e.g. 3/7.
+	ChecklistDone  int `json:"checklist_done"`
+	ChecklistTotal int `json:"checklist_total"`
+	// ChecklistAutoDone moves the task to done once every item is checked.
+	ChecklistAutoDone bool `json:"checklist_auto_done"`
+	// Rank orders the task within its column; SortIndex is its position.
+	Rank string `json:"rank"`
+	// Version goes up on every edit and is sent as the ETag.
+	Version int `json:"version"`
+	// RequiredSkills and RoleKey drive assignee suggestions.
+	RequiredSkills []string `json:"required_skills"`
+	RoleKey        *string  `json:"role_key"`
+	// SeriesID is set on occurrences of a recurring task; OccurrenceOn is
+	// the date (YYYY-MM-DD) this one stands for.
+	SeriesID     *string `json:"series_id"`
+	OccurrenceOn *string `json:"occurrence_on"`
 }
 
 // ========= Requests =========
@@ -32,14 +67,244 @@ type createTaskReq struct {
 	AssigneeID *string	`json:"assignee_id"`
 	Difficulty int		`json:"difficulty"`
 	SortIndex *int		`json:"sort_index"`
+	AfterID *string		`json:"after_id"` // place after this task in the column
+	BeforeID *string	`json:"before_id"` // or before this one
+	EpicID *string		`json:"epic_id"`
+	StartDate *string	`json:"start_date"` // YYYY-MM-DD
+	DueAt *string		`json:"due_at"` // RFC 3339 with offset
+
+	EstimateHours     *float64 `json:"estimate_hours"`
+	ChecklistAutoDone bool     `json:"checklist_auto_done"`
+
+	RequiredSkills []string `json:"required_skills"`
+	RoleKey        *string  `json:"role_key"`
+	// AutoAssign gives the task to the best suggested member when no
+	// assignee_id is sent.
+	AutoAssign bool `json:"auto_assign"`
+	// RRule makes the task the first occurrence of a recurring series, e.g.
+	// "FREQ=WEEKLY;COUNT=10". It starts on start_date, or today.
+	RRule *string `json:"rrule"`
+}
+
+// updateTaskPatch is an RFC 7396 merge patch for a task. Absent members are
+// left alone and an explicit null clears the field (or is rejected when the
+// field can't be empty). Position changes go through moveTaskReq instead.
+type updateTaskPatch struct {
+	Title      patchField[string] `json:"title"`
+	Details    patchField[string] `json:"details"`
+	Status     patchField[string] `json:"status"`
+	AssigneeID patchField[string] `json:"assignee_id"`
+	Difficulty patchField[int]    `json:"difficulty"`
+	EpicID     patchField[string] `json:"epic_id"`
+	StartDate  patchField[string] `json:"start_date"`
+	DueAt      patchField[string] `json:"due_at"`
+	// EstimateHours is the original estimate, in hours.
+	EstimateHours patchField[float64] `json:"estimate_hours"`
+	// Reason explains the status, e.g. why a task is blocked. Some workflow
+	// transitions require it.
+	Reason patchField[string] `json:"reason"`
+
+	ChecklistAutoDone patchField[bool] `json:"checklist_auto_done"`
+
+	RequiredSkills patchField[[]string] `json:"required_skills"`
+	RoleKey        patchField[string]   `json:"role_key"`
+
+	// SortIndex is only read so that sending it gets a pointer to the move
+	// endpoint rather than a generic unknown-field error.
+	SortIndex patchField[json.RawMessage] `json:"sort_index"`
+}
+
+type moveTaskReq struct {
+	Status    *string `json:"status"`
+	SortIndex *int    `json:"sort_index"`
+	AfterID   *string `json:"after_id"`
+	BeforeID  *string `json:"before_id"`
+	Reason    *string `json:"reason"`
+	// ProjectID moves the task to another project; AssigneeID then picks
+	// its assignee there.
+	ProjectID  *string `json:"project_id"`
+	AssigneeID *string `json:"assignee_id"`
+}
+
+// taskColumnsAt is the select list scanTask expects, with sortIndex as the
+// task's 0-based position in its column. Queries alias the task row as "t"
+// and left join the assignee as "usr".
+func taskColumnsAt(sortIndex string) string {
+	return `
+	t.id::text,
+	(select tp.key from projects tp where tp.id = t.project_id) || '-' || t.number,
+	t.number,
+	t.project_id::text,
+	t.epic_id::text,
+	t.title,
+	t.details,
+	t.status,
+	t.assignee_id::text,
+	usr.username,
+	t.status_reason,
+	t.difficulty,
+	` + sortIndex + `,
+	t.created_at,
+	t.completed_at,
+	(select count(*) from task_comments tc where tc.task_id = t.id and tc.deleted_at is null),
+	(select count(*) from task_checklist_items ci where ci.task_id = t.id and ci.done),
+	(select count(*) from task_checklist_items ci where ci.task_id = t.id),
+	t.checklist_auto_done,
+	array(
+		select d.blocker_id::text
+		from task_dependencies d
+		join tasks bt on bt.id = d.blocker_id
+		where d.blocked_id = t.id and bt.completed_at is null and bt.deleted_at is null
+		order by d.created_at
+	),
+	t.start_date,
+	t.due_at,
+	(t.due_at < now() and t.completed_at is null),
+	t.estimate_hours::float8,
+	(select coalesce(sum(w.hours), 0)::float8 from task_worklogs w where w.task_id = t.id),
+	t.rank,
+	t.version,
+	t.required_skills,
+	t.role_key,
+	t.series_id::text,
+	t.occurrence_on
+`
+}
+
+// taskColumns counts the tasks ahead of each row in its column. That is an
+// index range scan per row, so it suits queries returning a task or two;
+// listings use columnPosition or memberTaskPositions instead.
+var taskColumns = taskColumnsAt(`(
+		select count(*)::int from tasks o
+		where o.project_id = t.project_id and o.status = t.status
+			and o.deleted_at is null and o.id <> t.id and (o.rank, o.id) < (t.rank, t.id)
+	)`)
+
+// columnPosition numbers the rows a query returns within each column. It is
+// only right when the query returns whole columns, as the board does.
+const columnPosition = `(row_number() over (partition by t.project_id, t.status order by t.rank, t.id) - 1)::int`
+
+// memberTaskPositions joins pos.sort_index, each task's position in its
+// column, numbered once per query across the projects the user bound to
+// param is a member of. Listings that return only some of a column's tasks
+// select taskColumnsAt("pos.sort_index") with it.
+func memberTaskPositions(param string) string {
+	return `
+	join (
+		select id,
+			(row_number() over (partition by project_id, status order by rank, id) - 1)::int as sort_index
+		from tasks
+		where deleted_at is null
+			and project_id in (select project_id from projects_members where user_id = ` + param + `::uuid)
+	) pos on pos.id = t.id`
+}
+
+func scanTask(row pgx.Row, t *Task) error {
+	var createdAt time.Time
+	var completedAt, startDate, dueAt, occurrenceOn *time.Time
+	if err := row.Scan(
+		&t.ID,
+		&t.Key,
+		&t.Number,
+		&t.ProjectID,
+		&t.EpicID,
+		&t.Title,
+		&t.Details,
+		&t.Status,
+		&t.AssigneeID,
+		&t.AssigneeUsername,
+		&t.StatusReason,
+		&t.Difficulty,
+		&t.SortIndex,
+		&createdAt,
+		&completedAt,
+		&t.CommentCount,
+		&t.ChecklistDone,
+		&t.ChecklistTotal,
+		&t.ChecklistAutoDone,
+		&t.BlockedBy,
+		&startDate,
+		&dueAt,
+		&t.Overdue,
+		&t.EstimateHours,
+		&t.LoggedHours,
+		&t.Rank,
+		&t.Version,
+		&t.RequiredSkills,
+		&t.RoleKey,
+		&t.SeriesID,
+		&occurrenceOn,
+	); err != nil {
+		return err
+	}
+	t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	if completedAt != nil {
+		s := completedAt.UTC().Format(time.RFC3339)
+		t.CompletedAt = &s
+	}
+	if startDate != nil {
+		s := startDate.Format(time.DateOnly)
+		t.StartDate = &s
+	}
+	if dueAt != nil {
+		s := dueAt.UTC().Format(time.RFC3339)
+		t.DueAt = &s
+	}
+	if occurrenceOn != nil {
+		s := occurrenceOn.Format(time.DateOnly)
+		t.OccurrenceOn = &s
+	}
+	return nil
 }
 
-type updateTaskReq struct {
-    Details    *string `json:"details"`
-    Status     *string `json:"status"`
-    AssigneeID *string `json:"assignee_id"`
-    Difficulty *int    `json:"difficulty"`
-    SortIndex  *int    `json:"sort_index"`
+// extraColumns lets a query select more after taskColumns: the extra
+// destinations are scanned after the ones scanTask asks for.
+type extraColumns struct {
+	pgx.Row
+	dest []any
+}
+
+func (r extraColumns) Scan(dest ...any) error {
+	return r.Row.Scan(append(dest, r.dest...)...)
+}
+
+func scanAlso(row pgx.Row, dest ...any) pgx.Row {
+	return extraColumns{Row: row, dest: dest}
+}
+
+// loadTask reads one task in the shape the task endpoints return. Tasks in
+// the trash are not found.
+func loadTask(ctx context.Context, q dbtx, projectID, taskID uuid.UUID) (Task, error) {
+	var out Task
+	err := scanTask(q.QueryRow(ctx, `
+		select `+taskColumns+`
+		from tasks t
+		left join users usr on usr.id = t.assignee_id
+		where t.project_id = $1 and t.id = $2 and t.deleted_at is null
+	`, projectID, taskID), &out)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return out, newAPIError(http.StatusNotFound, "task not found")
+	}
+	return out, err
+}
+
+// taskInProject returns a 404 apiError unless the task belongs to the project.
+// Sub-resources (comments, attachments, ...) check it after membership so a
+// member of one project can't reach tasks in another through its URL.
+func taskInProject(ctx context.Context, q dbtx, projectID, taskID uuid.UUID) error {
+	var ok bool
+	if err := q.QueryRow(ctx, `
+		select exists (
+			select 1 from tasks
+			where id = $1 and project_id = $2 and deleted_at is null
+		)
+	`, taskID, projectID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return newAPIError(http.StatusNotFound, "task not found")
+	}
+	return nil
 }
 
 func (h *Handler) AddTask(c *gin.Context) {
@@ -76,13 +341,6 @@ func (h *Handler) AddTask(c *gin.Context) {
 	details := strings.TrimSpace(req.Details)
 
 	status := strings.TrimSpace(req.Status)
-	if status == "" {
-		status = "backlog"
-	}
-	if !isValidTaskStatus(status) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-		return
-	}
 
 	diff := req.Difficulty
 	if diff == 0 {
@@ -94,12 +352,70 @@ func (h *Handler) AddTask(c *gin.Context) {
 	}
 
 	// Normalize assignee: treat missing/blank as NULL (unassigned)
-	var assignee any = nil
-	if req.AssigneeID != nil {
-		a := strings.TrimSpace(*req.AssigneeID)
-		if a != "" {
-			assignee = a // UUID string; postgres will cast to uuid
+	assignee, err := parseAssignee(req.AssigneeID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var epicID *uuid.UUID
+	if req.EpicID != nil && strings.TrimSpace(*req.EpicID) != "" {
+		e, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*req.EpicID)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid epic id"})
+			return
 		}
+		epicID = &e
+	}
+
+	var startDate, dueAt *time.Time
+	if req.StartDate != nil && strings.TrimSpace(*req.StartDate) != "" {
+		d, err := parseStartDate(*req.StartDate)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		startDate = &d
+	}
+	if req.DueAt != nil && strings.TrimSpace(*req.DueAt) != "" {
+		d, err := parseDueAt(*req.DueAt)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		dueAt = &d
+	}
+	if err := checkTaskDates(startDate, dueAt); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := checkEstimate(req.EstimateHours); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	skills, err := normalizeSkills(req.RequiredSkills)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var roleKey *string
+	if req.RoleKey != nil {
+		if roleKey, err = normalizeRoleKey(*req.RoleKey); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	var rule *recurrence
+	if req.RRule != nil && strings.TrimSpace(*req.RRule) != "" {
+		r, err := parseRRule(*req.RRule)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		rule = &r
 	}
 
 	ctx, cancel := contextTimeout(c, 5*time.Second)
@@ -122,322 +438,753 @@ func (h *Handler) AddTask(c *gin.Context) {
 		return
 	}
 
+	if epicID != nil {
+		if err := epicInProject(ctx, h.DB, projectID, *epicID); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	wf, err := loadWorkflow(ctx, h.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if status == "" {
+		status = wf.defaultStatus()
+	}
+	if _, ok := wf.find(status); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
 	var out Task
-	var createdAt time.Time
 
-	var sortIndex *int
-	if req.SortIndex != nil {
-		si := *req.SortIndex
-		if si < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_index"})
+	place, err := parseRankPlace(req.SortIndex, req.AfterID, req.BeforeID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var picked *AssigneeSuggestion
+	if req.AutoAssign && assignee == nil {
+		candidates, err := suggestAssignees(ctx, tx, projectID, skills, roleKey, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 			return
 		}
-		sortIndex = &si
+		if len(candidates) > 0 {
+			picked = &candidates[0]
+			assignee = &picked.UserID
+		}
 	}
 
+	if assignee != nil {
+		if err := checkAssignee(ctx, tx, projectID, *assignee); err != nil {
+			writeError(c, err)
+			return
+		}
+	}
 
-	err := h.DB.QueryRow(ctx, `
-	with desired as (
-		select coalesce(
-			$7::int,
-			(select coalesce(max(sort_index), -1) + 1
-			from tasks
-			where project_id = $1 and status = $4)
-		) as idx
-	), shifted as (
-		update tasks
-		set sort_index = sort_index + 1
-		where project_id = $1
-			and status = $4
-			and $7::int is not null
-			and sort_index >= (select idx from desired)
-	), inserted as (
-		insert into tasks (project_id, title, details, status, assignee_id, difficulty, sort_index)
-		values ($1, $2, $3, $4, $5, $6, (select idx from desired))
+	violation, warnings, err := checkWip(ctx, tx, wipCheck{
+		ProjectID:    projectID,
+		Status:       status,
+		AssigneeID:   assignee,
+		EntersColumn: true,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if violation != nil {
+		writeError(c, wipViolationError(violation))
+		return
+	}
+
+	number, err := nextTaskNumber(ctx, tx, projectID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	column := taskRankList(projectID, status)
+	if err := lockRankScope(ctx, tx, column.Scope); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	rank, err := placeRank(ctx, tx, column, nil, place)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	row := tx.QueryRow(ctx, `
+	with inserted as (
+		insert into tasks (project_id, title, details, status, assignee_id, difficulty, rank, epic_id, completed_at, checklist_auto_done, start_date, due_at, estimate_hours, number, required_skills, role_key)
+		values ($1, $2, $3, $4, $5, $6, $7, $8,
+			case when $9::boolean then now() end, $10, $11, $12, $13, $14, $15, $16)
 		returning *
 	)
-	select inserted.id::text,
-		inserted.project_id::text,
-		inserted.title,
-		inserted.details,
-		inserted.status,
-		inserted.assignee_id::text,
-		u.username,
-		inserted.difficulty,
-		inserted.sort_index,
-		inserted.created_at
-	from inserted
-	left join users u on u.id = inserted.assignee_id
-	`, projectID, title, details, status, assignee, diff, sortIndex).
-	Scan(
-		&out.ID,
-		&out.ProjectID,
-		&out.Title,
-		&out.Details,
-		&out.Status,
-		&out.AssigneeID,
-		&out.AssigneeUsername,
-		&out.Difficulty,
-		&out.SortIndex,
-		&createdAt,
-	)
+	select `+taskColumns+`
+	from inserted t
+	left join users usr on usr.id = t.assignee_id
+	`, projectID, title, details, status, assignee, diff, rank, epicID, wf.isDone(status), req.ChecklistAutoDone, startDate, dueAt, req.EstimateHours, number, skills, roleKey)
+
+	if err := scanTask(row, &out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	taskID, err := uuid.Parse(out.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if rule != nil {
+		start := today()
+		if startDate != nil {
+			start = *startDate
+		}
+		if _, err := startSeries(ctx, tx, uid, projectID, taskID, *rule, start); err != nil {
+			writeError(c, err)
+			return
+		}
+		if out, err = loadTask(ctx, tx, projectID, taskID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
 
+	if assignee != nil {
+		if err := watchTask(ctx, tx, taskID, *assignee, watchAssigned); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+	if err := recordTaskEvent(ctx, tx, projectID, taskID, uid, taskEventCreated, taskDiff(nil, &out)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	unresolved, err := syncMentions(ctx, tx, uid, projectID, taskID, nil, out.Details)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
 
-	out.CreatedAt = createdAt.UTC().Format(time.RFC3339)
-	c.JSON(http.StatusOK, out)
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, taskWriteResp{Task: out, Warnings: warnings, AutoAssigned: picked, UnresolvedMentions: unresolved})
 }
 
+// UpdateTask applies a JSON merge patch (application/merge-patch+json) to a
+// task. A status change appends the task to the end of its new column; use
+// MoveTask to place it at a specific position. An If-Match naming an older
+// version is refused with 412 and the current task.
 func (h *Handler) UpdateTask(c *gin.Context) {
-	uidAny, ok := c.Get("uid")
+	uid, ok := getAuthUID(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth"})
 		return
 	}
-	uid, ok := uidAny.(string)
-	if !ok || uid == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "bad auth"})
+	projectUUID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskUUID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
 		return
 	}
 
-	projectIDStr := strings.TrimSpace(c.Param("projectId"))
-	taskIDStr := strings.TrimSpace(c.Param("taskId"))
-	if projectIDStr == "" || taskIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing project or task id"})
+	var patch updateTaskPatch
+	if err := decodeMergePatch(c.Request.Body, &patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json", "detail": err.Error()})
 		return
 	}
 
-	projectUUID, err := uuid.Parse(strings.ToLower(projectIDStr))
+	ch, err := patch.change()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		writeError(c, err)
 		return
 	}
-	taskUUID, err := uuid.Parse(strings.ToLower(taskIDStr))
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectUUID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	var req updateTaskReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+	if !checkTaskIfMatch(c, ctx, tx, projectUUID, taskUUID) {
 		return
 	}
 
-    // sortIndex is ALWAYS required (all edit modes provide it)
-	if req.SortIndex == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing sort_index"})
+	out, warnings, err := applyTaskChange(ctx, tx, uid, projectUUID, taskUUID, ch)
+	if err != nil {
+		writeError(c, err)
 		return
 	}
 
-	if *req.SortIndex < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_index"})
+	var unresolved []UnresolvedMention
+	if ch.Details != nil {
+		if unresolved, err = syncMentions(ctx, tx, uid, projectUUID, taskUUID, nil, out.Details); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
 
-    // Validate difficulty if provided
-	if req.Difficulty != nil {
-		if *req.Difficulty < 1 || *req.Difficulty > 5 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid difficulty"})
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, taskWriteResp{Task: out, Warnings: warnings, UnresolvedMentions: unresolved})
+}
+
+// MoveTask reorders a task within its column or moves it to another column at
+// a given position. With project_id it moves the task to another project
+// instead, at the end of the column unless a position is given.
+func (h *Handler) MoveTask(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectUUID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskUUID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req moveTaskReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	place, err := parseRankPlace(req.SortIndex, req.AfterID, req.BeforeID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if req.ProjectID != nil && strings.TrimSpace(*req.ProjectID) != "" {
+		to, err := parseTransferTarget(req.ProjectID, req.Status, place, req.AssigneeID, req.Reason)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if to.ProjectID != projectUUID {
+			h.moveToProject(c, uid, projectUUID, taskUUID, to)
 			return
 		}
 	}
+	if req.AssigneeID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "assignee_id only applies when moving to another project"})
+		return
+	}
 
-    // Validate status if provided
+	if !place.isSet() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing sort_index, after_id or before_id"})
+		return
+	}
+
+	ch := taskChange{Place: place}
 	if req.Status != nil {
-		s := strings.TrimSpace(*req.Status)
-		if !isValidTaskStatus(s) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-			return
+		st := strings.TrimSpace(*req.Status)
+		ch.Status = &st
+	}
+	if req.Reason != nil {
+		r := strings.TrimSpace(*req.Reason)
+		ch.ReasonSet = true
+		if r != "" {
+			ch.Reason = &r
 		}
-		*req.Status = s
 	}
 
 	ctx, cancel := contextTimeout(c, 8*time.Second)
 	defer cancel()
 
-	// Must be a project member
-	var allowed bool
-	if err := h.DB.QueryRow(ctx, `
-		select exists (
-			select 1
-			from projects_members
-			where project_id = $1
-			and user_id::text = $2
-		)
-	`, projectUUID, uid).Scan(&allowed); err != nil {
+	if !requireProjectMember(c, ctx, h.DB, projectUUID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
-	if !allowed {
-		c.JSON(http.StatusForbidden, gin.H{"error": "not a project member"})
+	defer tx.Rollback(ctx)
+
+	if !checkTaskIfMatch(c, ctx, tx, projectUUID, taskUUID) {
 		return
 	}
 
-	// Fetch current status + sort_index (needed for stable reindexing)
-	var oldStatus string
-	var oldIndex int
-	if err := h.DB.QueryRow(ctx, `
-		select status, sort_index
-		from tasks
-		where project_id = $1 and id = $2
-	`, projectUUID, taskUUID).Scan(&oldStatus, &oldIndex); err != nil {
-		if err == pgx.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
-			return
-		}
+	out, warnings, err := applyTaskChange(ctx, tx, uid, projectUUID, taskUUID, ch)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		return
 	}
 
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, taskWriteResp{Task: out, Warnings: warnings})
+}
+
+// taskChange is a validated edit to one task. nil pointers leave a field
+// alone; the *Set flags distinguish "clear" from "leave alone" for nullable
+// fields.
+type taskChange struct {
+	Title      *string
+	Details    *string
+	Difficulty *int
+
+	AssigneeSet bool
+	AssigneeID  *string
+
+	EpicSet bool
+	EpicID  *uuid.UUID
+
+	ReasonSet bool
+	Reason    *string
+
+	ChecklistAutoDone *bool
+
+	StartDateSet bool
+	StartDate    *time.Time
+	DueSet       bool
+	DueAt        *time.Time
+
+	EstimateSet   bool
+	EstimateHours *float64
+
+	RequiredSkills []string // nil leaves them alone
+	RoleSet        bool
+	RoleKey        *string
+
+	// Status and Place position the task. A status change without a Place
+	// appends to the end of the new column.
+	Status *string
+	Place  rankPlace
+}
+
+// change validates the patch and converts it into a taskChange.
+func (p updateTaskPatch) change() (taskChange, error) {
+	var ch taskChange
+
+	if p.SortIndex.Set {
+		return ch, newAPIError(http.StatusBadRequest, "sort_index can't be patched; use the move endpoint")
+	}
+
+	if p.Title.Set {
+		if p.Title.Null {
+			return ch, newAPIError(http.StatusBadRequest, "title cannot be null")
+		}
+		t := strings.TrimSpace(p.Title.Value)
+		if t == "" {
+			return ch, newAPIError(http.StatusBadRequest, "missing title")
+		}
+		ch.Title = &t
+	}
+
+	if p.Details.Set {
+		d := strings.TrimSpace(p.Details.Value) // null clears to ""
+		ch.Details = &d
+	}
+
+	if p.Status.Set {
+		if p.Status.Null {
+			return ch, newAPIError(http.StatusBadRequest, "status cannot be null")
+		}
+		st := strings.TrimSpace(p.Status.Value)
+		ch.Status = &st
+	}
+
+	if p.Difficulty.Set {
+		if p.Difficulty.Null {
+			return ch, newAPIError(http.StatusBadRequest, "difficulty cannot be null")
+		}
+		if p.Difficulty.Value < 1 || p.Difficulty.Value > 5 {
+			return ch, newAPIError(http.StatusBadRequest, "invalid difficulty")
+		}
+		d := p.Difficulty.Value
+		ch.Difficulty = &d
+	}
+
+	// null or "" unassigns
+	if p.AssigneeID.Set {
+		ch.AssigneeSet = true
+		if v := strings.TrimSpace(p.AssigneeID.Value); !p.AssigneeID.Null && v != "" {
+			a, err := uuid.Parse(strings.ToLower(v))
+			if err != nil {
+				return ch, newAPIError(http.StatusBadRequest, "invalid assignee id")
+			}
+			as := a.String()
+			ch.AssigneeID = &as
+		}
+	}
+
+	if p.EpicID.Set {
+		ch.EpicSet = true
+		if v := strings.TrimSpace(p.EpicID.Value); !p.EpicID.Null && v != "" {
+			e, err := uuid.Parse(strings.ToLower(v))
+			if err != nil {
+				return ch, newAPIError(http.StatusBadRequest, "invalid epic id")
+			}
+			ch.EpicID = &e
+		}
+	}
+
+	if p.Reason.Set {
+		ch.ReasonSet = true
+		if r := strings.TrimSpace(p.Reason.Value); !p.Reason.Null && r != "" {
+			ch.Reason = &r
+		}
+	}
+
+	// null or "" clears either date
+	if p.StartDate.Set {
+		ch.StartDateSet = true
+		if v := strings.TrimSpace(p.StartDate.Value); !p.StartDate.Null && v != "" {
+			d, err := parseStartDate(v)
+			if err != nil {
+				return ch, err
+			}
+			ch.StartDate = &d
+		}
+	}
+
+	if p.DueAt.Set {
+		ch.DueSet = true
+		if v := strings.TrimSpace(p.DueAt.Value); !p.DueAt.Null && v != "" {
+			d, err := parseDueAt(v)
+			if err != nil {
+				return ch, err
+			}
+			ch.DueAt = &d
+		}
+	}
+
+	// null clears the estimate
+	if p.EstimateHours.Set {
+		ch.EstimateSet = true
+		if !p.EstimateHours.Null {
+			v := p.EstimateHours.Value
+			if err := checkEstimate(&v); err != nil {
+				return ch, err
+			}
+			ch.EstimateHours = &v
+		}
+	}
+
+	if p.ChecklistAutoDone.Set {
+		v := p.ChecklistAutoDone.Value // null turns it off
+		ch.ChecklistAutoDone = &v
+	}
+
+	// null clears the skills
+	if p.RequiredSkills.Set {
+		skills, err := normalizeSkills(p.RequiredSkills.Value)
+		if err != nil {
+			return ch, err
+		}
+		ch.RequiredSkills = skills
+	}
+
+	if p.RoleKey.Set {
+		ch.RoleSet = true
+		if !p.RoleKey.Null {
+			r, err := normalizeRoleKey(p.RoleKey.Value)
+			if err != nil {
+				return ch, err
+			}
+			ch.RoleKey = r
+		}
+	}
+
+	return ch, nil
+}
+
+// applyTaskChange runs every rule a task edit is subject to (workflow,
+// transitions, WIP limits) and writes it inside tx. Client-facing failures
+// come back as *apiError.
+func applyTaskChange(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID, ch taskChange) (Task, []WipWarning, error) {
+	var out Task
+
+	// Lock the row so concurrent edits of the same task apply one at a time.
+	var oldStatus, oldDetails string
+	var oldAssignee *string
+	var oldStart, oldDue *time.Time
+	if err := tx.QueryRow(ctx, `
+		select status, details, assignee_id::text, start_date, due_at
+		from tasks
+		where project_id = $1 and id = $2 and deleted_at is null
+		for update
+	`, projectID, taskID).Scan(&oldStatus, &oldDetails, &oldAssignee, &oldStart, &oldDue); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return out, nil, newAPIError(http.StatusNotFound, "task not found")
+		}
+		return out, nil, err
+	}
+	before, err := loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		return out, nil, err
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return out, nil, err
+	}
+
 	newStatus := oldStatus
-	if req.Status != nil {
-		newStatus = *req.Status
+	if ch.Status != nil {
+		newStatus = *ch.Status
+		if _, ok := wf.find(newStatus); !ok {
+			return out, nil, newAPIError(http.StatusBadRequest, "invalid status")
+		}
 	}
-	newIndex := *req.SortIndex
 
+	if ch.EpicSet && ch.EpicID != nil {
+		if err := epicInProject(ctx, tx, projectID, *ch.EpicID); err != nil {
+			return out, nil, err
+		}
+	}
 
-	// Normalize fields
-	var newDetails *string
-	if req.Details != nil {
-		d := strings.TrimSpace(*req.Details)
-		newDetails = &d
+	newAssignee := oldAssignee
+	if ch.AssigneeSet {
+		newAssignee = ch.AssigneeID
+		if newAssignee != nil && !sameString(oldAssignee, newAssignee) {
+			if err := checkAssignee(ctx, tx, projectID, *newAssignee); err != nil {
+				return out, nil, err
+			}
+		}
 	}
-	
-	var newDiff *int
-	if req.Difficulty != nil { newDiff = req.Difficulty }
 
-    // Assignee behavior:
-	// - If field omitted => keep as-is
-	// - If provided as "" => set NULL (unassigned)
-	// - If provided as uuid string => set that uuid
-	assigneeMode := "keep" // keep | null | set
-	var assigneeVal any = nil
-	if req.AssigneeID != nil {
-		v := strings.TrimSpace(*req.AssigneeID)
-		if v == "" {
-			assigneeMode = "null"
-			assigneeVal = nil
-		} else {
+	if ch.StartDateSet || ch.DueSet {
+		start, due := oldStart, oldDue
+		if ch.StartDateSet {
+			start = ch.StartDate
+		}
+		if ch.DueSet {
+			due = ch.DueAt
+		}
+		if err := checkTaskDates(start, due); err != nil {
+			return out, nil, err
+		}
+	}
+
+	state := transitionState{
+		HasAssignee: newAssignee != nil,
+		Details:     oldDetails,
+	}
+	if ch.Details != nil {
+		state.Details = *ch.Details
+	}
+	if ch.Reason != nil {
+		state.Reason = *ch.Reason
+	}
+	if err := checkTransition(ctx, tx, projectID, uid, oldStatus, newStatus, state); err != nil {
+		return out, nil, err
+	}
+
+	finishing := wf.isDone(newStatus) && !wf.isDone(oldStatus)
+	if finishing {
+		if err := checkOpenBlockers(ctx, tx, taskID); err != nil {
+			return out, nil, err
+		}
+	}
+
+	violation, warnings, err := checkWip(ctx, tx, wipCheck{
+		ProjectID:       projectID,
+		Status:          newStatus,
+		TaskID:          &taskID,
+		AssigneeID:      newAssignee,
+		EntersColumn:    newStatus != oldStatus,
+		AssigneeChanged: !sameString(oldAssignee, newAssignee),
+	})
+	if err != nil {
+		return out, nil, err
+	}
+	if violation != nil {
+		return out, nil, wipViolationError(violation)
+	}
+
+	if newStatus != oldStatus || ch.Place.isSet() {
+		if err := moveTask(ctx, tx, projectID, taskID, newStatus, ch.Place); err != nil {
+			return out, nil, err
+		}
+	}
+
+	// A new status starts without a reason unless one is given.
+	reasonMode := "keep" // keep | null | set
+	if ch.ReasonSet {
+		reasonMode = "null"
+		if ch.Reason != nil {
+			reasonMode = "set"
+		}
+	} else if newStatus != oldStatus {
+		reasonMode = "null"
+	}
+
+	assigneeMode := "keep"
+	if ch.AssigneeSet {
+		assigneeMode = "null"
+		if ch.AssigneeID != nil {
 			assigneeMode = "set"
-			assigneeVal = strings.ToLower(v)
 		}
 	}
 
-	// Reindex + update in a single statement.
-	// This keeps sort_index unique within each (project_id, status) bucket.
+	epicMode := "keep"
+	if ch.EpicSet {
+		epicMode = "null"
+		if ch.EpicID != nil {
+			epicMode = "set"
+		}
+	}
 
-	var out Task
-	var createdAt time.Time
-	
-	err = h.DB.QueryRow(ctx, `
-		with cur as (
-			select id, project_id, status as old_status, sort_index as old_index
-			from tasks
-			where project_id = $1 and id = $2
-		),
-		move_same as (
-			-- Reorder within the same status
-			update tasks t
-			set sort_index = case
-				when (select $4::int) > (select old_index from cur)
-					and t.sort_index > (select old_index from cur)
-					and t.sort_index <= (select $4::int)
-					then t.sort_index - 1
-				when (select $4::int) < (select old_index from cur)
-					and t.sort_index >= (select $4::int)
-					and t.sort_index < (select old_index from cur)
-					then t.sort_index + 1
-				else t.sort_index
-			end
-			where t.project_id = $1
-				and t.status = (select old_status from cur)
-				and t.id <> (select id from cur)
-				and (select $3) = (select old_status from cur)
-		),
-		move_cross_old as (
-			-- Close gap in old status when changing status
-			update tasks t
-			set sort_index = t.sort_index - 1
-			where t.project_id = $1
-				and t.status = (select old_status from cur)
-				and t.sort_index > (select old_index from cur)
-				and (select $3) <> (select old_status from cur)
-		),
-		move_cross_new as (
-			-- Make room in new status when changing status
-			update tasks t
-			set sort_index = t.sort_index + 1
-			where t.project_id = $1
-				and t.status = (select $3)
-				and t.sort_index >= (select $4::int)
-				and (select $3) <> (select old_status from cur)
-		),
-		updated as (
+	err = scanTask(tx.QueryRow(ctx, `
+		with updated as (
 			update tasks
 			set
-				details = coalesce($5, details),
-				status = $3,
-				sort_index = $4::int,
-				difficulty = coalesce($6, difficulty),
+				title = coalesce($3, title),
+				details = coalesce($4, details),
+				difficulty = coalesce($5, difficulty),
 				assignee_id = case
-					when $7 = 'keep' then assignee_id
-					when $7 = 'null' then null
-					else $8::uuid
-				end
+					when $6 = 'keep' then assignee_id
+					when $6 = 'null' then null
+					else $7::uuid
+				end,
+				epic_id = case
+					when $8 = 'keep' then epic_id
+					when $8 = 'null' then null
+					else $9::uuid
+				end,
+				status_reason = case
+					when $10 = 'keep' then status_reason
+					when $10 = 'null' then null
+					else $11
+				end,
+				-- stamp the first move into a done column, clear it when reopened
+				completed_at = case
+					when not $12::boolean then null
+					else coalesce(completed_at, now())
+				end,
+				checklist_auto_done = coalesce($13, checklist_auto_done),
+				-- any move out of the auto-block column ends the auto-block
+				blocked_from = case when $14::boolean then null else blocked_from end,
+				start_date = case when $15::boolean then $16::date else start_date end,
+				due_at = case when $17::boolean then $18::timestamptz else due_at end,
+				estimate_hours = case when $19::boolean then $20::numeric else estimate_hours end,
+				required_skills = coalesce($21::text[], required_skills),
+				role_key = case when $22::boolean then $23 else role_key end,
+				version = version + 1
 			where project_id = $1 and id = $2
 			returning *
 		)
-		select
-			u.id::text,
-			u.project_id::text,
-			u.title,
-			u.details,
-			u.status,
-			u.assignee_id::text,
-			usr.username,
-			u.difficulty,
-			u.sort_index,
-			u.created_at
-		from updated u
-		left join users usr on usr.id = u.assignee_id
+		select `+taskColumns+`
+		from updated t
+		left join users usr on usr.id = t.assignee_id
 	`,
-		projectUUID,
-		taskUUID,
-		newStatus,
-		newIndex,
-		newDetails,
-		newDiff,
+		projectID,
+		taskID,
+		ch.Title,
+		ch.Details,
+		ch.Difficulty,
 		assigneeMode,
-		assigneeVal,
-	).Scan(
-		&out.ID,
-		&out.ProjectID,
-		&out.Title,
-		&out.Details,
-		&out.Status,
-		&out.AssigneeID,
-		&out.AssigneeUsername,
-		&out.Difficulty,
-		&out.SortIndex,
-		&createdAt,
-	)
-
+		ch.AssigneeID,
+		epicMode,
+		ch.EpicID,
+		reasonMode,
+		ch.Reason,
+		wf.isDone(newStatus),
+		ch.ChecklistAutoDone,
+		newStatus != oldStatus,
+		ch.StartDateSet,
+		ch.StartDate,
+		ch.DueSet,
+		ch.DueAt,
+		ch.EstimateSet,
+		ch.EstimateHours,
+		ch.RequiredSkills,
+		ch.RoleSet,
+		ch.RoleKey,
+	), &out)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
-			return
+		if errors.Is(err, pgx.ErrNoRows) {
+			return out, nil, newAPIError(http.StatusNotFound, "task not found")
 		}
+		return out, nil, err
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
-		return
+	// A new assignee starts watching in time to hear about it.
+	if out.AssigneeID != nil && !sameString(oldAssignee, out.AssigneeID) {
+		if err := watchTask(ctx, tx, taskID, *out.AssigneeID, watchAssigned); err != nil {
+			return out, nil, err
+		}
+	}
+
+	if diff := taskDiff(&before, &out); len(diff) > 0 {
+		if err := recordTaskEvent(ctx, tx, projectID, taskID, uid, eventKind(diff), diff); err != nil {
+			return out, nil, err
+		}
 	}
 
-	out.CreatedAt = createdAt.UTC().Format(time.RFC3339)
-	c.JSON(http.StatusOK, out)
+	if finishing {
+		if err := releaseBlocked(ctx, tx, uid, projectID, taskID); err != nil {
+			return out, nil, err
+		}
+		if out.SeriesID != nil {
+			if err := continueSeries(ctx, tx, uid, uuid.MustParse(*out.SeriesID)); err != nil {
+				return out, nil, err
+			}
+		}
+	}
+
+	return out, warnings, nil
+}
+
+// moveTask puts a task at place in the newStatus column. Only the task's
+// own row is written: it gets a rank between its new neighbours.
+func moveTask(ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID, newStatus string, place rankPlace) error {
+	column := taskRankList(projectID, newStatus)
+	if err := lockRankScope(ctx, tx, column.Scope); err != nil {
+		return err
+	}
+	rank, err := placeRank(ctx, tx, column, &taskID, place)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		update tasks
+		set status = $3,
+			rank = $4
+		where project_id = $1 and id = $2
+	`, projectID, taskID, newStatus, rank)
+	return err
 }
 
 func (h *Handler) DeleteTask(c *gin.Context) {
@@ -491,59 +1238,120 @@ func (h *Handler) DeleteTask(c *gin.Context) {
 	}
 
 	tx, err := h.DB.Begin(ctx)
-    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error":"server error"}); return }
-    defer tx.Rollback(ctx)
-
-    // 1) read the task's status + sort_index (and ensure it belongs to project)
-    var status string
-    var deletedSort int
-    err = tx.QueryRow(ctx, `
-        select status, sort_index
-        from tasks
-        where id::text = $1 and project_id::text = $2
-    `, taskUUID, projectUUID).Scan(&status, &deletedSort)
-    if err != nil {
-		if err == pgx.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error":"task not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error":"server error"}); 
-		return
-    }
-
-    // 2) delete the task
-    cmd, err := tx.Exec(ctx, `
-        delete from tasks
-        where id::text = $1 and project_id::text = $2
-    `, taskUUID, projectUUID)
-    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error":"server error"}); return }
-    if cmd.RowsAffected() == 0 {
-        c.JSON(http.StatusNotFound, gin.H{"error":"task not found"})
-        return
-    }
-
-    // 3) close the gap in that column
-    _, err = tx.Exec(ctx, `
-        update tasks
-        set sort_index = sort_index - 1
-        where project_id::text = $1
-			and status = $2
-			and sort_index > $3
-    `, projectUUID, status, deletedSort)
-    if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error":"server error"}); return }
-
-    if err := tx.Commit(ctx); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error":"server error"}); return
-    }
-
-    c.JSON(http.StatusOK, gin.H{"ok": true, "status": status})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	status, deletedAt, err := deleteTask(ctx, tx, uid, projectUUID, taskUUID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// The task waits in the trash; the client can offer an undo that calls
+	// POST .../restore until purge_at.
+	c.JSON(http.StatusOK, gin.H{
+		"ok":         true,
+		"status":     status,
+		"deleted_at": deletedAt.UTC().Format(time.RFC3339),
+		"purge_at":   deletedAt.Add(h.trashRetention()).UTC().Format(time.RFC3339),
+	})
 }
 
-func isValidTaskStatus(s string) bool {
-	switch s {
-	case "backlog", "inProgress", "blocked", "done":
-		return true
-	default:
-		return false
+// deleteTask moves a task to the trash inside tx: it records the deletion
+// and releases tasks it was blocking. The row and its attachments stay
+// until the task is restored or purged. It returns the task's status and
+// when it was deleted.
+func deleteTask(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID) (string, time.Time, error) {
+	var deletedAt time.Time
+
+	// 1) read the task (and ensure it belongs to project)
+	snapshot, err := loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		return "", deletedAt, err
 	}
-}
\ No newline at end of file
+	if err := recordTaskEvent(ctx, tx, projectID, taskID, uid, taskEventDeleted, taskDiff(&snapshot, nil)); err != nil {
+		return "", deletedAt, err
+	}
+
+	// tasks this one blocks; a task in the trash no longer blocks them
+	dependents, err := blockedTaskIDs(ctx, tx, taskID)
+	if err != nil {
+		return "", deletedAt, err
+	}
+
+	// 2) move the task to the trash
+	err = tx.QueryRow(ctx, `
+		update tasks
+		set deleted_at = now(),
+			deleted_by = $3::uuid,
+			version = version + 1
+		where id = $1 and project_id = $2 and deleted_at is null
+		returning deleted_at
+	`, taskID, projectID, uid).Scan(&deletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", deletedAt, newAPIError(http.StatusNotFound, "task not found")
+	}
+	if err != nil {
+		return "", deletedAt, err
+	}
+
+	if err := releaseTasks(ctx, tx, uid, projectID, dependents); err != nil {
+		return "", deletedAt, err
+	}
+
+	return snapshot.Status, deletedAt, nil
+}
+
+func parseStartDate(s string) (time.Time, error) {
+	d, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return d, newAPIError(http.StatusBadRequest, "invalid start_date (want YYYY-MM-DD)")
+	}
+	return d, nil
+}
+
+// parseDueAt wants a full timestamp with an offset, so a deadline means the
+// same instant for everyone on the project.
+func parseDueAt(s string) (time.Time, error) {
+	d, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return d, newAPIError(http.StatusBadRequest, "invalid due_at (want RFC 3339, e.g. 2025-06-01T17:00:00+02:00)")
+	}
+	return d, nil
+}
+
+// checkTaskDates rejects a start date after the due date. The due date is
+// read in the offset it was given in (UTC once stored).
+func checkTaskDates(start, due *time.Time) error {
+	if start == nil || due == nil {
+		return nil
+	}
+	dueDay, _ := time.Parse(time.DateOnly, due.Format(time.DateOnly))
+	if start.After(dueDay) {
+		return newAPIError(http.StatusBadRequest, "start_date is after due_at")
+	}
+	return nil
+}
+
+// checkEstimate allows up to 99999.99 hours, the column's range.
+func checkEstimate(h *float64) error {
+	if h != nil && (*h < 0 || *h >= 100000) {
+		return newAPIError(http.StatusBadRequest, "invalid estimate_hours")
+	}
+	return nil
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
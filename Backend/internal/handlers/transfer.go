@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Moving a task to another project keeps the task (its id, comments,
+// attachments, checklist, worklogs and history) and gives it a number, and so
+// a key, in the new project. Copying creates a new task with copies of all of
+// that except worklogs. Both need the caller to be a member of both projects.
+
+// ========= Requests =========
+type copyTaskReq struct {
+	ProjectID  *string `json:"project_id"` // defaults to the task's own project
+	Status     *string `json:"status"`
+	SortIndex  *int    `json:"sort_index"`
+	AfterID    *string `json:"after_id"`
+	BeforeID   *string `json:"before_id"`
+	AssigneeID *string `json:"assignee_id"`
+	Reason     *string `json:"reason"`
+}
+
+// taskTransferResp is a moved or copied task.
+type taskTransferResp struct {
+	taskWriteResp
+	// FromKey is the task's key before the move, or the original's key.
+	FromKey string `json:"from_key"`
+	// AssigneeCleared reports that the assignee isn't a member of the target
+	// project and was dropped.
+	AssigneeCleared bool `json:"assignee_cleared"`
+	// DependenciesRemoved counts links to tasks left behind by a move.
+	DependenciesRemoved int `json:"dependencies_removed"`
+}
+
+// transferTarget is the column a task is moved or copied into.
+type transferTarget struct {
+	ProjectID uuid.UUID
+	Status    string // "" for the project's default column
+	Place     rankPlace
+	// AssigneeSet picks AssigneeID (nil unassigns). Otherwise the current
+	// assignee is kept if they are a member of the target project.
+	AssigneeSet bool
+	AssigneeID  *string
+	Reason      *string
+}
+
+// placement is a transferTarget checked against the target project.
+type placement struct {
+	Status          string
+	Done            bool
+	Rank            string
+	Number          int
+	AssigneeID      *string
+	AssigneeCleared bool
+	Warnings        []WipWarning
+}
+
+// attachmentCopy is an attachment whose blob was copied for a new task.
+type attachmentCopy struct {
+	ID          uuid.UUID
+	Key         string
+	SourceKey   string
+	UploaderID  *string
+	Filename    string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+}
+
+func parseTransferTarget(projectID *string, status *string, place rankPlace, assigneeID *string, reason *string) (transferTarget, error) {
+	var t transferTarget
+	if projectID != nil && strings.TrimSpace(*projectID) != "" {
+		id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(*projectID)))
+		if err != nil {
+			return t, newAPIError(http.StatusBadRequest, "invalid project id")
+		}
+		t.ProjectID = id
+	}
+	if status != nil {
+		t.Status = strings.TrimSpace(*status)
+	}
+	t.Place = place
+	if assigneeID != nil {
+		a, err := parseAssignee(assigneeID)
+		if err != nil {
+			return t, err
+		}
+		t.AssigneeSet = true
+		t.AssigneeID = a
+	}
+	if reason != nil {
+		if r := strings.TrimSpace(*reason); r != "" {
+			t.Reason = &r
+		}
+	}
+	return t, nil
+}
+
+// prepareTransfer checks that the caller may put a task into the target
+// column and reserves its number and rank there. assignee is the task's
+// current one.
+func prepareTransfer(ctx context.Context, tx pgx.Tx, uid string, assignee *string, to transferTarget) (placement, error) {
+	var p placement
+
+	var member bool
+	if err := tx.QueryRow(ctx, `
+		select exists (
+			select 1 from projects_members
+			where project_id = $1 and user_id::text = $2
+		)
+	`, to.ProjectID, uid).Scan(&member); err != nil {
+		return p, err
+	}
+	if !member {
+		return p, newAPIError(http.StatusForbidden, "not a member of the target project")
+	}
+
+	wf, err := loadWorkflow(ctx, tx, to.ProjectID)
+	if err != nil {
+		return p, err
+	}
+	p.Status = to.Status
+	if p.Status == "" {
+		p.Status = wf.defaultStatus()
+	}
+	if _, ok := wf.find(p.Status); !ok {
+		return p, newAPIError(http.StatusBadRequest, "invalid status")
+	}
+	p.Done = wf.isDone(p.Status)
+
+	switch {
+	case to.AssigneeSet:
+		p.AssigneeID = to.AssigneeID
+		if p.AssigneeID != nil {
+			if err := checkAssignee(ctx, tx, to.ProjectID, *p.AssigneeID); err != nil {
+				return p, err
+			}
+		}
+	case assignee != nil:
+		err := checkAssignee(ctx, tx, to.ProjectID, *assignee)
+		var apiErr *apiError
+		switch {
+		case err == nil:
+			p.AssigneeID = assignee
+		case errors.As(err, &apiErr):
+			p.AssigneeCleared = true
+		default:
+			return p, err
+		}
+	}
+
+	violation, warnings, err := checkWip(ctx, tx, wipCheck{
+		ProjectID:       to.ProjectID,
+		Status:          p.Status,
+		AssigneeID:      p.AssigneeID,
+		EntersColumn:    true,
+		AssigneeChanged: p.AssigneeID != nil,
+	})
+	if err != nil {
+		return p, err
+	}
+	if violation != nil {
+		return p, wipViolationError(violation)
+	}
+	p.Warnings = warnings
+
+	if p.Number, err = nextTaskNumber(ctx, tx, to.ProjectID); err != nil {
+		return p, err
+	}
+
+	column := taskRankList(to.ProjectID, p.Status)
+	if err := lockRankScope(ctx, tx, column.Scope); err != nil {
+		return p, err
+	}
+	if p.Rank, err = placeRank(ctx, tx, column, nil, to.Place); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// dropNonMembers unassigns checklist items from, and removes watchers who
+// are, not members of projectID.
+func dropNonMembers(ctx context.Context, tx pgx.Tx, projectID, taskID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `
+		update task_checklist_items ci
+		set assignee_id = null
+		where ci.task_id = $2 and ci.assignee_id is not null
+			and not exists (
+				select 1 from projects_members pm
+				where pm.project_id = $1 and pm.user_id = ci.assignee_id
+			)
+	`, projectID, taskID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		delete from task_watchers w
+		where w.task_id = $2
+			and not exists (
+				select 1 from projects_members pm
+				where pm.project_id = $1 and pm.user_id = w.user_id
+			)
+	`, projectID, taskID)
+	return err
+}
+
+// moveTaskToProject moves a task, with everything attached to it, from one
+// project to another. Dependencies can't span projects, so the task's are
+// removed, and tasks it was blocking in the old project may be released.
+// Its epic and recurring series stay behind.
+func moveTaskToProject(ctx context.Context, tx pgx.Tx, uid string, from, taskID uuid.UUID, to transferTarget) (taskTransferResp, error) {
+	var out taskTransferResp
+
+	before, err := loadTask(ctx, tx, from, taskID)
+	if err != nil {
+		return out, err
+	}
+	p, err := prepareTransfer(ctx, tx, uid, before.AssigneeID, to)
+	if err != nil {
+		return out, err
+	}
+
+	dependents, err := blockedTaskIDs(ctx, tx, taskID)
+	if err != nil {
+		return out, err
+	}
+	cmd, err := tx.Exec(ctx, `
+		delete from task_dependencies
+		where blocker_id = $1 or blocked_id = $1
+	`, taskID)
+	if err != nil {
+		return out, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update tasks
+		set project_id = $3,
+			number = $4,
+			status = $5,
+			rank = $6,
+			assignee_id = $7::uuid,
+			status_reason = $8,
+			completed_at = case when $9::boolean then coalesce(completed_at, now()) end,
+			epic_id = null,
+			series_id = null,
+			occurrence_on = null,
+			blocked_from = null,
+			version = version + 1
+		where project_id = $1 and id = $2
+	`, from, taskID, to.ProjectID, p.Number, p.Status, p.Rank, p.AssigneeID, to.Reason, p.Done); err != nil {
+		return out, err
+	}
+
+	// History and notifications follow the task.
+	if _, err := tx.Exec(ctx, `
+		update task_events set project_id = $2 where task_id = $1
+	`, taskID, to.ProjectID); err != nil {
+		return out, err
+	}
+	if _, err := tx.Exec(ctx, `
+		update notifications set project_id = $2 where task_id = $1
+	`, taskID, to.ProjectID); err != nil {
+		return out, err
+	}
+	if err := dropNonMembers(ctx, tx, to.ProjectID, taskID); err != nil {
+		return out, err
+	}
+
+	after, err := loadTask(ctx, tx, to.ProjectID, taskID)
+	if err != nil {
+		return out, err
+	}
+	if after.AssigneeID != nil && !sameString(before.AssigneeID, after.AssigneeID) {
+		if err := watchTask(ctx, tx, taskID, *after.AssigneeID, watchAssigned); err != nil {
+			return out, err
+		}
+	}
+	diff := taskDiff(&before, &after)
+	diff["key"] = FieldChange{From: before.Key, To: after.Key}
+	if err := recordTaskEvent(ctx, tx, to.ProjectID, taskID, uid, eventKind(diff), diff); err != nil {
+		return out, err
+	}
+
+	// Mentions are resolved against the new project's members.
+	unresolved, err := syncMentions(ctx, tx, uid, to.ProjectID, taskID, nil, after.Details)
+	if err != nil {
+		return out, err
+	}
+
+	if err := releaseTasks(ctx, tx, uid, from, dependents); err != nil {
+		return out, err
+	}
+
+	out.Task = after
+	out.Warnings = p.Warnings
+	out.UnresolvedMentions = unresolved
+	out.FromKey = before.Key
+	out.AssigneeCleared = p.AssigneeCleared
+	out.DependenciesRemoved = int(cmd.RowsAffected())
+	return out, nil
+}
+
+// copyTask creates newID in the target project as a copy of a task, with
+// its checklist, comments, history and the already copied attachments.
+func copyTask(ctx context.Context, tx pgx.Tx, uid string, from, taskID, newID uuid.UUID, to transferTarget, attachments []attachmentCopy) (taskTransferResp, error) {
+	var out taskTransferResp
+
+	src, err := loadTask(ctx, tx, from, taskID)
+	if err != nil {
+		return out, err
+	}
+	p, err := prepareTransfer(ctx, tx, uid, src.AssigneeID, to)
+	if err != nil {
+		return out, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		insert into tasks (id, project_id, title, details, status, status_reason, assignee_id, difficulty, rank,
+			epic_id, completed_at, checklist_auto_done, start_date, due_at, estimate_hours, number,
+			required_skills, role_key)
+		select $3, $4, s.title, s.details, $5, $6, $7::uuid, s.difficulty, $8,
+			case when $4 = $1 then s.epic_id end,
+			case when $9::boolean then now() end,
+			s.checklist_auto_done, s.start_date, s.due_at, s.estimate_hours, $10,
+			s.required_skills, s.role_key
+		from tasks s
+		where s.project_id = $1 and s.id = $2
+	`, from, taskID, newID, to.ProjectID, p.Status, to.Reason, p.AssigneeID, p.Rank, p.Done, p.Number); err != nil {
+		return out, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		insert into task_checklist_items (task_id, body, done, assignee_id, sort_index, created_at)
+		select $2, ci.body, ci.done, ci.assignee_id, ci.sort_index, ci.created_at
+		from task_checklist_items ci
+		where ci.task_id = $1
+	`, taskID, newID); err != nil {
+		return out, err
+	}
+
+	// Replies point at the copies of their parents.
+	if _, err := tx.Exec(ctx, `
+		with m as (
+			select id, gen_random_uuid() as new_id
+			from task_comments
+			where task_id = $1
+		)
+		insert into task_comments (id, task_id, author_id, parent_id, body, created_at, edited_at, deleted_at)
+		select m.new_id, $2, cm.author_id, pm.new_id, cm.body, cm.created_at, cm.edited_at, cm.deleted_at
+		from task_comments cm
+		join m on m.id = cm.id
+		left join m pm on pm.id = cm.parent_id
+	`, taskID, newID); err != nil {
+		return out, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		insert into task_events (project_id, task_id, actor_id, kind, changes, created_at)
+		select $3, $2, e.actor_id, e.kind, e.changes, e.created_at
+		from task_events e
+		where e.task_id = $1
+		order by e.created_at, e.seq
+	`, taskID, newID, to.ProjectID); err != nil {
+		return out, err
+	}
+
+	if len(attachments) > 0 {
+		ids := make([]uuid.UUID, len(attachments))
+		uploaders := make([]*string, len(attachments))
+		names := make([]string, len(attachments))
+		types := make([]string, len(attachments))
+		sizes := make([]int64, len(attachments))
+		keys := make([]string, len(attachments))
+		created := make([]time.Time, len(attachments))
+		for i, a := range attachments {
+			ids[i], uploaders[i], names[i], types[i] = a.ID, a.UploaderID, a.Filename, a.ContentType
+			sizes[i], keys[i], created[i] = a.Size, a.Key, a.CreatedAt
+		}
+		if _, err := tx.Exec(ctx, `
+			insert into task_attachments (id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at)
+			select a.id, $1, a.uploader_id, a.filename, a.content_type, a.size_bytes, a.storage_key, a.created_at
+			from unnest($2::uuid[], $3::uuid[], $4::text[], $5::text[], $6::bigint[], $7::text[], $8::timestamptz[])
+				as a(id, uploader_id, filename, content_type, size_bytes, storage_key, created_at)
+		`, newID, ids, uploaders, names, types, sizes, keys, created); err != nil {
+			return out, err
+		}
+	}
+
+	if err := dropNonMembers(ctx, tx, to.ProjectID, newID); err != nil {
+		return out, err
+	}
+
+	task, err := loadTask(ctx, tx, to.ProjectID, newID)
+	if err != nil {
+		return out, err
+	}
+
+	if task.AssigneeID != nil {
+		if err := watchTask(ctx, tx, newID, *task.AssigneeID, watchAssigned); err != nil {
+			return out, err
+		}
+	}
+	diff := taskDiff(nil, &task)
+	diff["copied_from"] = FieldChange{To: src.Key}
+	if err := recordTaskEvent(ctx, tx, to.ProjectID, newID, uid, taskEventCreated, diff); err != nil {
+		return out, err
+	}
+	unresolved, err := syncMentions(ctx, tx, uid, to.ProjectID, newID, nil, task.Details)
+	if err != nil {
+		return out, err
+	}
+
+	out.Task = task
+	out.Warnings = p.Warnings
+	out.UnresolvedMentions = unresolved
+	out.FromKey = src.Key
+	out.AssigneeCleared = p.AssigneeCleared
+	return out, nil
+}
+
+// copyAttachmentBlobs copies the blobs behind a task's attachments for the
+// copy newID. On failure the blobs copied so far are removed again.
+func (h *Handler) copyAttachmentBlobs(ctx context.Context, taskID, newID uuid.UUID) ([]attachmentCopy, error) {
+	rows, err := h.DB.Query(ctx, `
+		select uploader_id::text, filename, content_type, size_bytes, storage_key, created_at
+		from task_attachments
+		where task_id = $1
+		order by created_at
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]attachmentCopy, 0)
+	for rows.Next() {
+		a := attachmentCopy{ID: uuid.New()}
+		if err := rows.Scan(&a.UploaderID, &a.Filename, &a.ContentType, &a.Size, &a.SourceKey, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Key = attachmentKey(newID, a.ID)
+		out = append(out, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+	if h.Blobs == nil {
+		return nil, newAPIError(http.StatusServiceUnavailable, "attachments are not configured")
+	}
+
+	copied := make([]string, 0, len(out))
+	for _, a := range out {
+		if err := h.copyBlob(ctx, a); err != nil {
+			log.Printf("attachments: copy blob %s: %v", a.SourceKey, err)
+			h.removeBlobs(copied)
+			return nil, newAPIError(http.StatusBadGateway, "storage error")
+		}
+		copied = append(copied, a.Key)
+	}
+	return out, nil
+}
+
+func (h *Handler) copyBlob(ctx context.Context, a attachmentCopy) error {
+	r, err := h.Blobs.Get(ctx, a.SourceKey)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return h.Blobs.Put(ctx, a.Key, r, a.Size, a.ContentType)
+}
+
+// moveToProject is MoveTask with a project_id naming another project.
+func (h *Handler) moveToProject(c *gin.Context, uid string, projectID, taskID uuid.UUID, to transferTarget) {
+	ctx, cancel := contextTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if !checkTaskIfMatch(c, ctx, tx, projectID, taskID) {
+		return
+	}
+
+	out, err := moveTaskToProject(ctx, tx, uid, projectID, taskID, to)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, out)
+}
+
+// CopyTask copies a task, into another project or its own, with its
+// checklist, comments, attachments and history. Worklogs, dependencies and
+// recurrence aren't copied.
+func (h *Handler) CopyTask(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req copyTaskReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	place, err := parseRankPlace(req.SortIndex, req.AfterID, req.BeforeID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	to, err := parseTransferTarget(req.ProjectID, req.Status, place, req.AssigneeID, req.Reason)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if to.ProjectID == uuid.Nil {
+		to.ProjectID = projectID
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		cancel()
+		return
+	}
+	err = taskInProject(ctx, h.DB, projectID, taskID)
+	cancel()
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// Blobs are copied before the transaction, as uploads are, and dropped
+	// again if it fails.
+	newID := uuid.New()
+	blobCtx, blobCancel := contextTimeout(c, 5*time.Minute)
+	defer blobCancel()
+	attachments, err := h.copyAttachmentBlobs(blobCtx, taskID, newID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	copied := make([]string, len(attachments))
+	for i, a := range attachments {
+		copied[i] = a.Key
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			h.removeBlobs(copied)
+		}
+	}()
+
+	ctx, cancel = contextTimeout(c, 10*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	out, err := copyTask(ctx, tx, uid, projectID, taskID, newID, to, attachments)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	committed = true
+
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, out)
+}
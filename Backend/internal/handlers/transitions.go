@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Transition DTOs (responses) =========
+type StatusTransition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Roles limits who may make the move: roleKey values, or "owner" for the
+	// project owner. Empty means any member.
+	Roles []string `json:"roles"`
+	// RequiredFields must be present after the move: reason | assignee | details.
+	RequiredFields []string `json:"required_fields"`
+}
+
+// ========= Requests =========
+type updateTransitionsReq struct {
+	Transitions []StatusTransition `json:"transitions"`
+}
+
+var transitionFields = map[string]bool{
+	"reason":   true,
+	"assignee": true,
+	"details":  true,
+}
+
+// transitionRules is a project's allow-list. A project without any rules
+// allows every move, which is how boards behaved before rules existed.
+type transitionRules []StatusTransition
+
+func (r transitionRules) restricted() bool {
+	return len(r) > 0
+}
+
+// permits reports whether role may use t. The owner may always use a rule
+// that lists "owner"; otherwise the member's roleKey must be listed.
+func (t StatusTransition) permits(role string, isOwner bool) bool {
+	if len(t.Roles) == 0 {
+		return true
+	}
+	for _, r := range t.Roles {
+		if r == role || (r == "owner" && isOwner) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the rule for from -> to usable by the caller, plus every
+// target the caller could move to from "from" (for the 409 body).
+func (r transitionRules) lookup(from, to, role string, isOwner bool) (*StatusTransition, []string) {
+	var match *StatusTransition
+	allowed := make([]string, 0)
+	for i := range r {
+		t := r[i]
+		if t.From != from || !t.permits(role, isOwner) {
+			continue
+		}
+		allowed = append(allowed, t.To)
+		if t.To == to {
+			match = &r[i]
+		}
+	}
+	return match, allowed
+}
+
+// transitionState is what a task looks like after the pending update, used to
+// check a rule's required fields.
+type transitionState struct {
+	Reason      string
+	HasAssignee bool
+	Details     string
+}
+
+func (t StatusTransition) missingFields(s transitionState) []string {
+	missing := make([]string, 0)
+	for _, f := range t.RequiredFields {
+		switch f {
+		case "reason":
+			if strings.TrimSpace(s.Reason) == "" {
+				missing = append(missing, f)
+			}
+		case "assignee":
+			if !s.HasAssignee {
+				missing = append(missing, f)
+			}
+		case "details":
+			if strings.TrimSpace(s.Details) == "" {
+				missing = append(missing, f)
+			}
+		}
+	}
+	return missing
+}
+
+func loadTransitions(ctx context.Context, q dbtx, projectID uuid.UUID) (transitionRules, error) {
+	rows, err := q.Query(ctx, `
+		select from_status, to_status, allowed_roles, required_fields
+		from status_transitions
+		where project_id = $1
+		order by from_status asc, to_status asc
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(transitionRules, 0)
+	for rows.Next() {
+		var t StatusTransition
+		if err := rows.Scan(&t.From, &t.To, &t.Roles, &t.RequiredFields); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// memberRole returns the caller's roleKey in the project and whether they own it.
+func memberRole(ctx context.Context, q dbtx, projectID uuid.UUID, uid string) (string, bool, error) {
+	var role string
+	var isOwner bool
+	err := q.QueryRow(ctx, `
+		select pm.rolekey, p.owner_id = pm.user_id
+		from projects_members pm
+		join projects p on p.id = pm.project_id
+		where pm.project_id = $1 and pm.user_id::text = $2
+	`, projectID, uid).Scan(&role, &isOwner)
+	return role, isOwner, err
+}
+
+// checkTransition checks a status change against the project's rules. It
+// returns a 409 apiError when the move is not allowed and a 422 when the
+// rule needs fields the task won't have. Staying in the same status is
+// always allowed.
+func checkTransition(ctx context.Context, q dbtx, projectID uuid.UUID, uid, from, to string, state transitionState) error {
+	if from == to {
+		return nil
+	}
+
+	rules, err := loadTransitions(ctx, q, projectID)
+	if err != nil {
+		return err
+	}
+	if !rules.restricted() {
+		return nil
+	}
+
+	role, isOwner, err := memberRole(ctx, q, projectID, uid)
+	if err != nil {
+		return err
+	}
+
+	rule, allowed := rules.lookup(from, to, role, isOwner)
+	if rule == nil {
+		return &apiError{Status: http.StatusConflict, Body: gin.H{
+			"error":   "transition not allowed",
+			"from":    from,
+			"to":      to,
+			"allowed": allowed,
+		}}
+	}
+
+	if missing := rule.missingFields(state); len(missing) > 0 {
+		return &apiError{Status: http.StatusUnprocessableEntity, Body: gin.H{
+			"error":  "transition requires fields",
+			"from":   from,
+			"to":     to,
+			"fields": missing,
+		}}
+	}
+
+	return nil
+}
+
+func (h *Handler) GetTransitions(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	rules, err := loadTransitions(ctx, h.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transitions": rules})
+}
+
+// UpdateTransitions replaces the project's allow-list. Sending an empty list
+// lifts all restrictions.
+func (h *Handler) UpdateTransitions(c *gin.Context) {
+	ownerID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	var req updateTransitionsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var locked string
+	if err := tx.QueryRow(ctx, `
+		select id::text from projects
+		where id = $1 and owner_id = $2::uuid
+		for update
+	`, projectID, ownerID).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rules := make(transitionRules, 0, len(req.Transitions))
+	seen := make(map[[2]string]bool, len(req.Transitions))
+	for _, t := range req.Transitions {
+		t.From = strings.TrimSpace(t.From)
+		t.To = strings.TrimSpace(t.To)
+		if _, ok := wf.find(t.From); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status", "status": t.From})
+			return
+		}
+		if _, ok := wf.find(t.To); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status", "status": t.To})
+			return
+		}
+		if t.From == t.To {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transition must change status"})
+			return
+		}
+		pair := [2]string{t.From, t.To}
+		if seen[pair] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate transition", "from": t.From, "to": t.To})
+			return
+		}
+		seen[pair] = true
+
+		roles := make([]string, 0, len(t.Roles))
+		for _, r := range t.Roles {
+			if r = strings.TrimSpace(r); r != "" {
+				roles = append(roles, r)
+			}
+		}
+		t.Roles = roles
+
+		fields := make([]string, 0, len(t.RequiredFields))
+		for _, f := range t.RequiredFields {
+			f = strings.TrimSpace(f)
+			if !transitionFields[f] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid required field", "field": f})
+				return
+			}
+			fields = append(fields, f)
+		}
+		t.RequiredFields = fields
+
+		rules = append(rules, t)
+	}
+
+	if _, err := tx.Exec(ctx, `delete from status_transitions where project_id = $1`, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	for _, t := range rules {
+		if _, err := tx.Exec(ctx, `
+			insert into status_transitions (project_id, from_status, to_status, allowed_roles, required_fields)
+			values ($1, $2, $3, $4, $5)
+		`, projectID, t.From, t.To, t.Roles, t.RequiredFields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transitions": rules})
+}
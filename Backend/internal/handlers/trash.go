@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTrashRetention applies when the handler isn't given one.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+const (
+	defaultTrashLimit = 50
+	maxTrashLimit     = 200
+)
+
+// ========= Trash DTOs (responses) =========
+
+// TrashedTask is a deleted task waiting to be restored or purged.
+type TrashedTask struct {
+	Task
+	DeletedAt         string  `json:"deleted_at"`
+	DeletedByID       *string `json:"deleted_by_id"`
+	DeletedByUsername *string `json:"deleted_by_username"`
+	// PurgeAt is when the task is removed for good.
+	PurgeAt string `json:"purge_at"`
+}
+
+func (h *Handler) trashRetention() time.Duration {
+	if h.TrashRetention > 0 {
+		return h.TrashRetention
+	}
+	return DefaultTrashRetention
+}
+
+// ListTrash lists a project's deleted tasks, most recently deleted first.
+// They have left their columns, so sort_index is 0.
+func (h *Handler) ListTrash(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	limit, ok := queryInt(c, "limit", defaultTrashLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxTrashLimit {
+		limit = maxTrashLimit
+	}
+	offset, ok := queryInt(c, "offset", 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	var total int
+	if err := h.DB.QueryRow(ctx, `
+		select count(*) from tasks
+		where project_id = $1 and deleted_at is not null
+	`, projectID).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+taskColumnsAt("0")+`, t.deleted_at, t.deleted_by::text, du.username
+		from tasks t
+		left join users usr on usr.id = t.assignee_id
+		left join users du on du.id = t.deleted_by
+		where t.project_id = $1 and t.deleted_at is not null
+		order by t.deleted_at desc, t.id
+		limit $2 offset $3
+	`, projectID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	retention := h.trashRetention()
+	tasks := make([]TrashedTask, 0)
+	for rows.Next() {
+		var t TrashedTask
+		var deletedAt time.Time
+		if err := scanTask(scanAlso(rows, &deletedAt, &t.DeletedByID, &t.DeletedByUsername), &t.Task); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		t.DeletedAt = deletedAt.UTC().Format(time.RFC3339)
+		t.PurgeAt = deletedAt.Add(retention).UTC().Format(time.RFC3339)
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks, "total": total, "limit": limit, "offset": offset})
+}
+
+// RestoreTask takes a task out of the trash. It goes back to its status, or
+// the default one if that status is gone, at the end of the column.
+func (h *Handler) RestoreTask(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	out, warnings, err := restoreTask(ctx, tx, uid, projectID, taskID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	setETag(c, out.Version)
+	c.JSON(http.StatusOK, taskWriteResp{Task: out, Warnings: warnings})
+}
+
+// restoreTask brings a deleted task back inside tx. It is subject to the
+// column's WIP limits like any task entering it.
+func restoreTask(ctx context.Context, tx pgx.Tx, uid string, projectID, taskID uuid.UUID) (Task, []WipWarning, error) {
+	var out Task
+
+	var status string
+	var assignee *string
+	err := tx.QueryRow(ctx, `
+		select status, assignee_id::text
+		from tasks
+		where project_id = $1 and id = $2 and deleted_at is not null
+		for update
+	`, projectID, taskID).Scan(&status, &assignee)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return out, nil, newAPIError(http.StatusNotFound, "task not in trash")
+	}
+	if err != nil {
+		return out, nil, err
+	}
+
+	wf, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		return out, nil, err
+	}
+	if _, ok := wf.find(status); !ok {
+		status = wf.defaultStatus()
+	}
+
+	violation, warnings, err := checkWip(ctx, tx, wipCheck{
+		ProjectID:       projectID,
+		Status:          status,
+		TaskID:          &taskID,
+		AssigneeID:      assignee,
+		EntersColumn:    true,
+		AssigneeChanged: true,
+	})
+	if err != nil {
+		return out, nil, err
+	}
+	if violation != nil {
+		return out, nil, wipViolationError(violation)
+	}
+
+	column := taskRankList(projectID, status)
+	if err := lockRankScope(ctx, tx, column.Scope); err != nil {
+		return out, nil, err
+	}
+	rank, err := placeRank(ctx, tx, column, &taskID, rankPlace{})
+	if err != nil {
+		return out, nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		update tasks
+		set status = $3,
+			rank = $4,
+			completed_at = case
+				when not $5::boolean then null
+				else coalesce(completed_at, now())
+			end,
+			deleted_at = null,
+			deleted_by = null,
+			version = version + 1
+		where project_id = $1 and id = $2
+	`, projectID, taskID, status, rank, wf.isDone(status)); err != nil {
+		return out, nil, err
+	}
+
+	out, err = loadTask(ctx, tx, projectID, taskID)
+	if err != nil {
+		return out, nil, err
+	}
+	if err := recordTaskEvent(ctx, tx, projectID, taskID, uid, taskEventRestored, taskDiff(nil, &out)); err != nil {
+		return out, nil, err
+	}
+	return out, warnings, nil
+}
+
+// PurgeTrash deletes tasks that have been in the trash longer than the
+// retention, then removes their attachment blobs.
+func (h *Handler) PurgeTrash(ctx context.Context) error {
+	cutoff := time.Now().Add(-h.trashRetention())
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var ids []uuid.UUID
+	if err := tx.QueryRow(ctx, `
+		select array(
+			select id from tasks
+			where deleted_at < $1
+			order by id
+			for update
+		)
+	`, cutoff).Scan(&ids); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var blobKeys []string
+	if err := tx.QueryRow(ctx, `
+		select array(select storage_key from task_attachments where task_id = any($1))
+	`, ids).Scan(&blobKeys); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `delete from tasks where id = any($1)`, ids); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	h.removeBlobs(blobKeys)
+	return nil
+}
+
+// RunTrashPurger calls PurgeTrash now and then every interval until ctx is
+// done.
+func (h *Handler) RunTrashPurger(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		runCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if err := h.PurgeTrash(runCtx); err != nil {
+			log.Printf("trash purge: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
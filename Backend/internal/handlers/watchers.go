@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Why someone watches a task.
+const (
+	watchManual    = "manual"
+	watchAssigned  = "assigned"
+	watchCommented = "commented"
+)
+
+// What a notification is about.
+const (
+	notificationTaskEvent = "task_event" // a change in the task's history
+	notificationComment   = "comment"    // a new comment on a watched task
+	notificationMention   = "mention"    // @username in the task's details or a comment
+)
+
+const (
+	defaultNotificationLimit = 30
+	maxNotificationLimit     = 100
+)
+
+// mentionRe finds @username not glued to a word before it, so an email
+// address isn't read as a mention.
+var mentionRe = regexp.MustCompile(`(?:^|[^\w@])@([\w.-]+)`)
+
+// ========= Watcher DTOs (responses) =========
+type Watcher struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Reason    string `json:"reason"` // manual | assigned | commented
+	CreatedAt string `json:"created_at"`
+}
+
+type Mention struct {
+	ID          string  `json:"id"`
+	TaskID      string  `json:"task_id"`
+	CommentID   *string `json:"comment_id"` // null for the task's details
+	UserID      string  `json:"user_id"`
+	Username    string  `json:"username"`
+	MentionedBy *string `json:"mentioned_by"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// UnresolvedMention is an @username that didn't match a project member.
+type UnresolvedMention struct {
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+}
+
+type Notification struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // task_event | comment | mention
+	ProjectID string `json:"project_id"`
+	TaskID    string `json:"task_id"`
+	// TaskKey and TaskTitle are null once the task is trashed or deleted.
+	TaskKey       *string `json:"task_key"`
+	TaskTitle     *string `json:"task_title"`
+	ActorID       *string `json:"actor_id"`
+	ActorUsername *string `json:"actor_username"`
+	// EventKind and Changes describe a task_event notification.
+	EventKind *string                `json:"event_kind"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	CommentID *string                `json:"comment_id"`
+	Read      bool                   `json:"read"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int            `json:"total"`
+	Unread        int            `json:"unread"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+}
+
+// ========= Requests =========
+type markNotificationsReq struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
+
+// parseMentions returns the lowercased usernames mentioned in text, each
+// once, in order. Trailing dots and dashes are punctuation, not part of the
+// name ("thanks @ana.").
+func parseMentions(text string) []string {
+	out := make([]string, 0)
+	seen := map[string]bool{}
+	for _, m := range mentionRe.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// watchTask subscribes userID to the task. Already watching is fine; the
+// original reason is kept.
+func watchTask(ctx context.Context, q dbtx, taskID uuid.UUID, userID, reason string) error {
+	_, err := q.Exec(ctx, `
+		insert into task_watchers (task_id, user_id, reason)
+		values ($1, $2::uuid, $3)
+		on conflict (task_id, user_id) do nothing
+	`, taskID, userID, reason)
+	return err
+}
+
+// syncMentions stores the mentions in text, the task's details when
+// commentID is nil or else that comment's body, replacing what was stored
+// for it before. Members mentioned for the first time are notified; names
+// that aren't members are returned.
+func syncMentions(ctx context.Context, tx pgx.Tx, actor string, projectID, taskID uuid.UUID, commentID *uuid.UUID, text string) ([]UnresolvedMention, error) {
+	names := parseMentions(text)
+
+	rows, err := tx.Query(ctx, `
+		select n.name, u.id::text, pm.user_id is not null
+		from unnest($2::text[]) with ordinality as n(name, i)
+		left join users u on lower(u.username) = n.name
+		left join projects_members pm on pm.project_id = $1 and pm.user_id = u.id
+		order by n.i
+	`, projectID, names)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]string, 0, len(names))
+	unresolved := make([]UnresolvedMention, 0)
+	for rows.Next() {
+		var name string
+		var userID *string
+		var member bool
+		if err := rows.Scan(&name, &userID, &member); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		switch {
+		case userID == nil:
+			unresolved = append(unresolved, UnresolvedMention{Username: name, Reason: "no such user"})
+		case !member:
+			unresolved = append(unresolved, UnresolvedMention{Username: name, Reason: "not a project member"})
+		default:
+			resolved = append(resolved, *userID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		delete from task_mentions
+		where task_id = $1 and comment_id is not distinct from $2
+			and not (user_id = any($3::uuid[]))
+	`, taskID, commentID, resolved); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		with added as (
+			insert into task_mentions (task_id, comment_id, user_id, mentioned_by)
+			select $1, $2, r.user_id, nullif($4, '')::uuid
+			from unnest($3::uuid[]) as r(user_id)
+			where not exists (
+				select 1 from task_mentions m
+				where m.task_id = $1 and m.comment_id is not distinct from $2 and m.user_id = r.user_id
+			)
+			returning user_id
+		)
+		insert into notifications (user_id, project_id, task_id, kind, comment_id, actor_id)
+		select a.user_id, $5, $1, 'mention', $2, nullif($4, '')::uuid
+		from added a
+		where a.user_id::text <> $4
+	`, taskID, commentID, resolved, actor, projectID); err != nil {
+		return nil, err
+	}
+
+	return unresolved, nil
+}
+
+// notifyComment tells the task's watchers about a new comment, except its
+// author and anyone it mentions (they get a mention instead).
+func notifyComment(ctx context.Context, q dbtx, actor string, projectID, taskID, commentID uuid.UUID) error {
+	_, err := q.Exec(ctx, `
+		insert into notifications (user_id, project_id, task_id, kind, comment_id, actor_id)
+		select w.user_id, $1, $2, 'comment', $3, $4::uuid
+		from task_watchers w
+		where w.task_id = $2 and w.user_id <> $4::uuid
+			and not exists (
+				select 1 from task_mentions m
+				where m.comment_id = $3 and m.user_id = w.user_id
+			)
+	`, projectID, taskID, commentID, actor)
+	return err
+}
+
+func (h *Handler) ListWatchers(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select w.user_id::text, u.username, w.reason, w.created_at
+		from task_watchers w
+		join users u on u.id = w.user_id
+		where w.task_id = $1
+		order by w.created_at, u.username
+	`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	watchers := make([]Watcher, 0)
+	watching := false
+	for rows.Next() {
+		var w Watcher
+		var createdAt time.Time
+		if err := rows.Scan(&w.UserID, &w.Username, &w.Reason, &createdAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		w.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		watching = watching || w.UserID == uid
+		watchers = append(watchers, w)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watchers": watchers, "watching": watching})
+}
+
+// WatchTask subscribes the caller to a task's changes and comments.
+func (h *Handler) WatchTask(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if err := watchTask(ctx, h.DB, taskID, uid, watchManual); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "watching": true})
+}
+
+// UnwatchTask unsubscribes the caller. Being assigned or commenting again
+// subscribes them again.
+func (h *Handler) UnwatchTask(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if _, err := h.DB.Exec(ctx, `
+		delete from task_watchers
+		where task_id = $1 and user_id = $2::uuid
+	`, taskID, uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "watching": false})
+}
+
+// ListMentions lists who is mentioned in a task's details and comments.
+func (h *Handler) ListMentions(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select m.id::text, m.task_id::text, m.comment_id::text, m.user_id::text, u.username,
+			m.mentioned_by::text, m.created_at
+		from task_mentions m
+		join users u on u.id = m.user_id
+		where m.task_id = $1
+		order by m.created_at, m.id
+	`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	mentions := make([]Mention, 0)
+	for rows.Next() {
+		var m Mention
+		var createdAt time.Time
+		if err := rows.Scan(&m.ID, &m.TaskID, &m.CommentID, &m.UserID, &m.Username, &m.MentionedBy, &createdAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		m.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		mentions = append(mentions, m)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mentions)
+}
+
+// ListNotifications pages through the caller's notifications, newest first.
+// unread=true leaves out the ones already read.
+func (h *Handler) ListNotifications(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := queryInt(c, "limit", defaultNotificationLimit)
+	if !ok || limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+	offset, ok := queryInt(c, "offset", 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	page := NotificationPage{Notifications: []Notification{}, Limit: limit, Offset: offset}
+	if err := h.DB.QueryRow(ctx, `
+		select
+			count(*) filter (where not $2::boolean or read_at is null),
+			count(*) filter (where read_at is null)
+		from notifications
+		where user_id = $1::uuid
+	`, uid, unreadOnly).Scan(&page.Total, &page.Unread); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select
+			n.id::text,
+			n.kind,
+			n.project_id::text,
+			n.task_id::text,
+			(select p.key from projects p where p.id = t.project_id) || '-' || t.number,
+			t.title,
+			n.actor_id::text,
+			au.username,
+			e.kind,
+			e.changes,
+			n.comment_id::text,
+			n.read_at is not null,
+			n.created_at
+		from notifications n
+		left join tasks t on t.id = n.task_id and t.deleted_at is null
+		left join users au on au.id = n.actor_id
+		left join task_events e on e.id = n.event_id
+		where n.user_id = $1::uuid
+			and (not $2::boolean or n.read_at is null)
+		order by n.created_at desc, n.id desc
+		limit $3 offset $4
+	`, uid, unreadOnly, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n Notification
+		var changes []byte
+		var createdAt time.Time
+		if err := rows.Scan(
+			&n.ID,
+			&n.Kind,
+			&n.ProjectID,
+			&n.TaskID,
+			&n.TaskKey,
+			&n.TaskTitle,
+			&n.ActorID,
+			&n.ActorUsername,
+			&n.EventKind,
+			&changes,
+			&n.CommentID,
+			&n.Read,
+			&createdAt,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		if changes != nil {
+			if err := json.Unmarshal(changes, &n.Changes); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+		}
+		n.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		page.Notifications = append(page.Notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// MarkNotificationsRead marks the listed notifications, or with all=true
+// every one, as read.
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+
+	var req markNotificationsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+	if !req.All && len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing ids"})
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	for _, raw := range req.IDs {
+		id, err := uuid.Parse(strings.ToLower(strings.TrimSpace(raw)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	cmd, err := h.DB.Exec(ctx, `
+		update notifications
+		set read_at = now()
+		where user_id = $1::uuid and read_at is null
+			and ($2::boolean or id = any($3::uuid[]))
+	`, uid, req.All, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "marked": cmd.RowsAffected()})
+}
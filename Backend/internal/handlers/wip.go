@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= WIP DTOs (responses) =========
+
+// WipWarning describes a column (or one assignee within it) that is over its
+// limit after a write.
+type WipWarning struct {
+	Status     string  `json:"status"`
+	Scope      string  `json:"scope"` // column | assignee
+	AssigneeID *string `json:"assignee_id,omitempty"`
+	Mode       string  `json:"mode"`
+	Limit      int     `json:"limit"`
+	Count      int     `json:"count"`
+}
+
+// WipUsage is a column's current load versus its limits, shown with the board.
+type WipUsage struct {
+	Status           string         `json:"status"`
+	Mode             string         `json:"mode"`
+	Limit            *int           `json:"limit"`
+	Count            int            `json:"count"`
+	PerAssigneeLimit *int           `json:"per_assignee_limit"`
+	ByAssignee       map[string]int `json:"by_assignee"`
+	Over             bool           `json:"over"`
+}
+
+// taskWriteResp is a task plus any soft-limit warnings the write produced.
+type taskWriteResp struct {
+	Task
+	Warnings []WipWarning `json:"warnings,omitempty"`
+	// AutoAssigned explains the pick when the task was created with
+	// auto_assign.
+	AutoAssigned *AssigneeSuggestion `json:"auto_assigned,omitempty"`
+	// UnresolvedMentions are @usernames in details that aren't members.
+	UnresolvedMentions []UnresolvedMention `json:"unresolved_mentions,omitempty"`
+}
+
+// wipCheck describes a task landing in a column. TaskID is nil for new tasks.
+type wipCheck struct {
+	ProjectID       uuid.UUID
+	Status          string
+	TaskID          *uuid.UUID
+	AssigneeID      *string
+	EntersColumn    bool
+	AssigneeChanged bool
+}
+
+// checkWip must run inside the transaction that writes the task. It locks the
+// column's workflow row so concurrent writes into the same column queue up
+// behind each other, then counts what the column would hold afterwards.
+// A non-nil violation means a hard limit was exceeded and the write must be
+// abandoned; warnings are soft-limit breaches to return to the caller.
+func checkWip(ctx context.Context, tx pgx.Tx, in wipCheck) (*WipWarning, []WipWarning, error) {
+	if !in.EntersColumn && !in.AssigneeChanged {
+		return nil, nil, nil
+	}
+
+	var limit, perAssignee *int
+	var mode string
+	err := tx.QueryRow(ctx, `
+		select wip_limit, wip_per_assignee, wip_mode
+		from project_statuses
+		where project_id = $1 and key = $2
+		for update
+	`, in.ProjectID, in.Status).Scan(&limit, &perAssignee, &mode)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	breaches := make([]WipWarning, 0)
+
+	if limit != nil && in.EntersColumn {
+		var n int
+		if err := tx.QueryRow(ctx, `
+			select count(*)
+			from tasks
+			where project_id = $1
+				and status = $2
+				and deleted_at is null
+				and ($3::uuid is null or id <> $3)
+		`, in.ProjectID, in.Status, in.TaskID).Scan(&n); err != nil {
+			return nil, nil, err
+		}
+		if n+1 > *limit {
+			breaches = append(breaches, WipWarning{
+				Status: in.Status,
+				Scope:  "column",
+				Mode:   mode,
+				Limit:  *limit,
+				Count:  n + 1,
+			})
+		}
+	}
+
+	if perAssignee != nil && in.AssigneeID != nil {
+		var n int
+		if err := tx.QueryRow(ctx, `
+			select count(*)
+			from tasks
+			where project_id = $1
+				and status = $2
+				and assignee_id = $3::uuid
+				and deleted_at is null
+				and ($4::uuid is null or id <> $4)
+		`, in.ProjectID, in.Status, *in.AssigneeID, in.TaskID).Scan(&n); err != nil {
+			return nil, nil, err
+		}
+		if n+1 > *perAssignee {
+			breaches = append(breaches, WipWarning{
+				Status:     in.Status,
+				Scope:      "assignee",
+				AssigneeID: in.AssigneeID,
+				Mode:       mode,
+				Limit:      *perAssignee,
+				Count:      n + 1,
+			})
+		}
+	}
+
+	if len(breaches) > 0 && mode == "hard" {
+		return &breaches[0], nil, nil
+	}
+	return nil, breaches, nil
+}
+
+func wipViolationError(v *WipWarning) error {
+	return &apiError{Status: http.StatusConflict, Body: gin.H{
+		"error": "wip limit reached",
+		"wip":   v,
+	}}
+}
+
+// loadWipCounts fills Count on each status and returns usage for the board.
+func loadWipCounts(ctx context.Context, q dbtx, projectID uuid.UUID, w workflow) ([]WipUsage, error) {
+	rows, err := q.Query(ctx, `
+		select status, coalesce(assignee_id::text, ''), count(*)
+		from tasks
+		where project_id = $1 and deleted_at is null
+		group by status, assignee_id
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int)
+	byAssignee := make(map[string]map[string]int)
+	for rows.Next() {
+		var status, assignee string
+		var n int
+		if err := rows.Scan(&status, &assignee, &n); err != nil {
+			return nil, err
+		}
+		totals[status] += n
+		if assignee != "" {
+			if byAssignee[status] == nil {
+				byAssignee[status] = make(map[string]int)
+			}
+			byAssignee[status][assignee] = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]WipUsage, 0, len(w))
+	for i := range w {
+		s := &w[i]
+		s.Count = totals[s.Key]
+
+		u := WipUsage{
+			Status:           s.Key,
+			Mode:             s.WipMode,
+			Limit:            s.WipLimit,
+			Count:            s.Count,
+			PerAssigneeLimit: s.WipPerAssignee,
+			ByAssignee:       map[string]int{},
+		}
+		if s.WipLimit != nil && s.Count > *s.WipLimit {
+			u.Over = true
+		}
+		if s.WipPerAssignee != nil {
+			for a, n := range byAssignee[s.Key] {
+				u.ByAssignee[a] = n
+				if n > *s.WipPerAssignee {
+					u.Over = true
+				}
+			}
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Workflow DTOs (responses) =========
+type WorkflowStatus struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Category  string `json:"category"` // todo | active | done
+	SortIndex int    `json:"sort_index"`
+
+	// WIP limits are optional; WipMode is soft (warn) or hard (reject).
+	WipLimit       *int   `json:"wip_limit"`
+	WipPerAssignee *int   `json:"wip_per_assignee"`
+	WipMode        string `json:"wip_mode"`
+	// Count is the number of tasks currently in the column (read endpoints only).
+	Count int `json:"count"`
+}
+
+// ========= Requests =========
+type workflowStatusReq struct {
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	Category       string `json:"category"`
+	WipLimit       *int   `json:"wip_limit"`
+	WipPerAssignee *int   `json:"wip_per_assignee"`
+	WipMode        string `json:"wip_mode"`
+}
+
+type updateWorkflowReq struct {
+	Statuses []workflowStatusReq `json:"statuses"`
+	// Remap moves tasks out of removed statuses: old key -> new key. Removed
+	// statuses without an entry fall back to the first new status of the
+	// same category.
+	Remap map[string]string `json:"remap"`
+}
+
+// defaultWorkflow is what every project starts with; it matches the four
+// statuses the board originally hard-coded.
+var defaultWorkflow = []WorkflowStatus{
+	{Key: "backlog", Name: "Backlog", Category: "todo", SortIndex: 0, WipMode: "soft"},
+	{Key: "inProgress", Name: "In Progress", Category: "active", SortIndex: 1, WipMode: "soft"},
+	{Key: "blocked", Name: "Blocked", Category: "active", SortIndex: 2, WipMode: "soft"},
+	{Key: "done", Name: "Done", Category: "done", SortIndex: 3, WipMode: "soft"},
+}
+
+var statusKeyRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,31}$`)
+
+// workflow is a project's ordered status list.
+type workflow []WorkflowStatus
+
+func (w workflow) find(key string) (WorkflowStatus, bool) {
+	for _, s := range w {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
+
+// defaultStatus is where tasks land when no status is given: the first todo
+// column.
+func (w workflow) defaultStatus() string {
+	for _, s := range w {
+		if s.Category == "todo" {
+			return s.Key
+		}
+	}
+	return w[0].Key
+}
+
+// doneStatus is the first done column, or "" if the workflow has none.
+func (w workflow) doneStatus() string {
+	for _, s := range w {
+		if s.Category == "done" {
+			return s.Key
+		}
+	}
+	return ""
+}
+
+func (w workflow) isDone(key string) bool {
+	s, ok := w.find(key)
+	return ok && s.Category == "done"
+}
+
+func loadWorkflow(ctx context.Context, q dbtx, projectID uuid.UUID) (workflow, error) {
+	rows, err := q.Query(ctx, `
+		select key, name, category, sort_index, wip_limit, wip_per_assignee, wip_mode
+		from project_statuses
+		where project_id = $1
+		order by sort_index asc, created_at asc
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	w := make(workflow, 0, len(defaultWorkflow))
+	for rows.Next() {
+		var s WorkflowStatus
+		if err := rows.Scan(&s.Key, &s.Name, &s.Category, &s.SortIndex, &s.WipLimit, &s.WipPerAssignee, &s.WipMode); err != nil {
+			return nil, err
+		}
+		w = append(w, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(w) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return w, nil
+}
+
+func insertWorkflow(ctx context.Context, q dbtx, projectID string, w []WorkflowStatus) error {
+	keys := make([]string, len(w))
+	names := make([]string, len(w))
+	cats := make([]string, len(w))
+	limits := make([]*int, len(w))
+	perAssignee := make([]*int, len(w))
+	modes := make([]string, len(w))
+	for i, s := range w {
+		keys[i], names[i], cats[i] = s.Key, s.Name, s.Category
+		limits[i], perAssignee[i], modes[i] = s.WipLimit, s.WipPerAssignee, s.WipMode
+	}
+	_, err := q.Exec(ctx, `
+		insert into project_statuses (project_id, key, name, category, sort_index, wip_limit, wip_per_assignee, wip_mode)
+		select $1::uuid, s.key, s.name, s.category, s.ord - 1, s.wip_limit, s.wip_per_assignee, s.wip_mode
+		from unnest($2::text[], $3::text[], $4::text[], $5::int[], $6::int[], $7::text[])
+			with ordinality as s(key, name, category, wip_limit, wip_per_assignee, wip_mode, ord)
+	`, projectID, keys, names, cats, limits, perAssignee, modes)
+	return err
+}
+
+func (h *Handler) GetWorkflow(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	w, err := loadWorkflow(ctx, h.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	rules, err := loadTransitions(ctx, h.DB, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	wip, err := loadWipCounts(ctx, h.DB, projectID, w)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	var autoBlock *string
+	if err := h.DB.QueryRow(ctx, `
+		select auto_block_status from projects where id = $1
+	`, projectID).Scan(&autoBlock); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statuses":          w,
+		"transitions":       rules,
+		"wip":               wip,
+		"auto_block_status": autoBlock,
+	})
+}
+
+// UpdateWorkflow replaces the project's columns. Tasks sitting in a removed
+// status are remapped and appended to the end of their new column.
+func (h *Handler) UpdateWorkflow(c *gin.Context) {
+	ownerID, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	var req updateWorkflowReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	if len(req.Statuses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing statuses"})
+		return
+	}
+
+	next := make(workflow, 0, len(req.Statuses))
+	hasTodo, hasDone := false, false
+	for i, s := range req.Statuses {
+		key := strings.TrimSpace(s.Key)
+		if !statusKeyRe.MatchString(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status key", "key": key})
+			return
+		}
+		if _, dup := next.find(key); dup {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate status key", "key": key})
+			return
+		}
+		name := strings.TrimSpace(s.Name)
+		if name == "" {
+			name = key
+		}
+		category := strings.TrimSpace(s.Category)
+		switch category {
+		case "todo":
+			hasTodo = true
+		case "done":
+			hasDone = true
+		case "active":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category", "key": key})
+			return
+		}
+		if (s.WipLimit != nil && *s.WipLimit < 1) || (s.WipPerAssignee != nil && *s.WipPerAssignee < 1) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wip limit", "key": key})
+			return
+		}
+		mode := strings.TrimSpace(s.WipMode)
+		if mode == "" {
+			mode = "soft"
+		}
+		if mode != "soft" && mode != "hard" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wip_mode", "key": key})
+			return
+		}
+		next = append(next, WorkflowStatus{
+			Key:            key,
+			Name:           name,
+			Category:       category,
+			SortIndex:      i,
+			WipLimit:       s.WipLimit,
+			WipPerAssignee: s.WipPerAssignee,
+			WipMode:        mode,
+		})
+	}
+	if !hasTodo || !hasDone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workflow needs at least one todo and one done status"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 10*time.Second)
+	defer cancel()
+
+	tx, err := h.DB.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Only the owner edits project settings; the lock serializes concurrent
+	// workflow edits.
+	var locked string
+	if err := tx.QueryRow(ctx, `
+		select id::text from projects
+		where id = $1 and owner_id = $2::uuid
+		for update
+	`, projectID, ownerID).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	prev, err := loadWorkflow(ctx, tx, projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Work out where every removed status goes.
+	oldKeys := make([]string, 0)
+	newKeys := make([]string, 0)
+	unmapped := make([]string, 0)
+	for _, s := range prev {
+		if _, kept := next.find(s.Key); kept {
+			continue
+		}
+		target, explicit := req.Remap[s.Key]
+		if explicit {
+			if _, ok := next.find(target); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid remap target", "key": s.Key, "target": target})
+				return
+			}
+		} else {
+			for _, n := range next {
+				if n.Category == s.Category {
+					target = n.Key
+					break
+				}
+			}
+			if target == "" {
+				unmapped = append(unmapped, s.Key)
+				continue
+			}
+		}
+		oldKeys = append(oldKeys, s.Key)
+		newKeys = append(newKeys, target)
+	}
+	if len(unmapped) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "removed statuses need a remap", "statuses": unmapped})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `delete from project_statuses where project_id = $1`, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if err := insertWorkflow(ctx, tx, projectID.String(), next); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	// Rules that mention a removed status no longer apply.
+	keys := make([]string, len(next))
+	for i, s := range next {
+		keys[i] = s.Key
+	}
+	if _, err := tx.Exec(ctx, `
+		delete from status_transitions
+		where project_id = $1
+			and (from_status <> all($2::text[]) or to_status <> all($2::text[]))
+	`, projectID, keys); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	// Same for the auto-block column, or a column that became a done one.
+	if _, err := tx.Exec(ctx, `
+		update projects p
+		set auto_block_status = null
+		where p.id = $1
+			and p.auto_block_status is not null
+			and not exists (
+				select 1 from project_statuses s
+				where s.project_id = p.id
+					and s.key = p.auto_block_status
+					and s.category <> 'done'
+			)
+	`, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if len(oldKeys) > 0 {
+		if _, err := tx.Exec(ctx, `
+			with m(old_key, new_key) as (
+				select * from unnest($3::text[], $4::text[])
+			), events as (
+				insert into task_events (project_id, task_id, actor_id, kind, changes)
+				select t.project_id, t.id, $2::uuid, 'moved',
+					jsonb_build_object('status', jsonb_build_object('from', m.old_key, 'to', m.new_key))
+				from tasks t
+				join m on m.old_key = t.status
+				where t.project_id = $1 and t.deleted_at is null
+				returning id, task_id
+			)
+			insert into notifications (user_id, project_id, task_id, kind, event_id, actor_id)
+			select w.user_id, $1, e.task_id, 'task_event', e.id, $2::uuid
+			from events e
+			join task_watchers w on w.task_id = e.task_id
+			where w.user_id <> $2::uuid
+		`, projectID, ownerID, oldKeys, newKeys); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+
+		// Append remapped tasks after everything already in the target
+		// column: prefixing their ranks with the column's last rank sorts
+		// them after it, in their old order. Respacing then shortens the
+		// grown ranks again.
+		// Lock in a fixed order so concurrent edits can't deadlock.
+		targets := slices.Compact(slices.Sorted(slices.Values(newKeys)))
+		for _, k := range targets {
+			if err := lockRankScope(ctx, tx, taskRankScope(projectID, k)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+		}
+		if _, err := tx.Exec(ctx, `
+			with m(old_key, new_key) as (
+				select * from unnest($2::text[], $3::text[])
+			), last as (
+				select status, max(rank) as rank
+				from tasks
+				where project_id = $1 and deleted_at is null
+				group by status
+			)
+			update tasks t
+			set status = m.new_key,
+				rank = coalesce(l.rank, '') || t.rank,
+				version = t.version + 1
+			from m
+			left join last l on l.status = m.new_key
+			where t.project_id = $1 and t.status = m.old_key
+		`, projectID, oldKeys, newKeys); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		for _, k := range targets {
+			if err := rebalanceRanks(ctx, tx, taskRankList(projectID, k)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+				return
+			}
+		}
+	}
+
+	// Categories may have changed, so re-derive completion stamps.
+	if _, err := tx.Exec(ctx, `
+		update tasks t
+		set completed_at = case
+			when s.category = 'done' then coalesce(t.completed_at, now())
+			else null
+		end,
+			version = t.version + 1
+		from project_statuses s
+		where t.project_id = $1
+			and s.project_id = t.project_id
+			and s.key = t.status
+			and (s.category = 'done') <> (t.completed_at is not null)
+	`, projectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	remapped := make(map[string]string, len(oldKeys))
+	for i := range oldKeys {
+		remapped[oldKeys[i]] = newKeys[i]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"statuses": next, "remapped": remapped})
+}
@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// ========= Worklog DTOs (responses) =========
+type Worklog struct {
+	ID        string  `json:"id"`
+	TaskID    string  `json:"task_id"`
+	UserID    *string `json:"user_id"`
+	Username  *string `json:"username"`
+	Hours     float64 `json:"hours"`
+	Date      string  `json:"date"` // YYYY-MM-DD
+	Note      string  `json:"note"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+type MemberHours struct {
+	UserID   *string `json:"user_id"`
+	Username *string `json:"username"`
+	Hours    float64 `json:"hours"`
+}
+
+type TaskHours struct {
+	TaskID        string   `json:"task_id"`
+	Title         string   `json:"title"`
+	EstimateHours *float64 `json:"estimate_hours"`
+	Hours         float64  `json:"hours"`
+}
+
+type WorklogTotals struct {
+	From     *string       `json:"from"`
+	To       *string       `json:"to"`
+	Hours    float64       `json:"hours"`
+	ByMember []MemberHours `json:"by_member"`
+	ByTask   []TaskHours   `json:"by_task"`
+}
+
+// ========= Requests =========
+type createWorklogReq struct {
+	Hours float64 `json:"hours"`
+	Date  string  `json:"date"` // defaults to today (UTC)
+	Note  string  `json:"note"`
+}
+
+type updateWorklogPatch struct {
+	Hours patchField[float64] `json:"hours"`
+	Date  patchField[string]  `json:"date"`
+	Note  patchField[string]  `json:"note"`
+}
+
+const maxWorklogNote = 2000
+
+const worklogColumns = `
+	w.id::text,
+	w.task_id::text,
+	w.user_id::text,
+	wu.username,
+	w.hours::float8,
+	w.work_date,
+	w.note,
+	w.created_at,
+	w.updated_at
+`
+
+func scanWorklog(row pgx.Row, w *Worklog) error {
+	var date, createdAt, updatedAt time.Time
+	if err := row.Scan(
+		&w.ID,
+		&w.TaskID,
+		&w.UserID,
+		&w.Username,
+		&w.Hours,
+		&date,
+		&w.Note,
+		&createdAt,
+		&updatedAt,
+	); err != nil {
+		return err
+	}
+	w.Date = date.Format(time.DateOnly)
+	w.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	w.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// validWorklogHours matches the column: more than zero, at most one day.
+func validWorklogHours(h float64) bool {
+	return h > 0 && h <= 24
+}
+
+func parseWorklogDate(s string) (time.Time, bool) {
+	d, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	return d, err == nil
+}
+
+func (h *Handler) ListWorklogs(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	rows, err := h.DB.Query(ctx, `
+		select `+worklogColumns+`
+		from task_worklogs w
+		left join users wu on wu.id = w.user_id
+		where w.task_id = $1
+		order by w.work_date desc, w.created_at desc
+	`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	out := make([]Worklog, 0)
+	total := 0.0
+	for rows.Next() {
+		var w Worklog
+		if err := scanWorklog(rows, &w); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		total += w.Hours
+		out = append(out, w)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"worklogs": out, "hours": total})
+}
+
+// AddWorklog logs time against a task for the caller.
+func (h *Handler) AddWorklog(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+
+	var req createWorklogReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	if !validWorklogHours(req.Hours) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hours"})
+		return
+	}
+
+	date := time.Now().UTC()
+	if strings.TrimSpace(req.Date) != "" {
+		d, ok := parseWorklogDate(req.Date)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date (want YYYY-MM-DD)"})
+			return
+		}
+		date = d
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if len([]rune(note)) > maxWorklogNote {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "note too long"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var out Worklog
+	if err := scanWorklog(h.DB.QueryRow(ctx, `
+		with inserted as (
+			insert into task_worklogs (task_id, user_id, hours, work_date, note)
+			values ($1, $2::uuid, $3, $4::date, $5)
+			returning *
+		)
+		select `+worklogColumns+`
+		from inserted w
+		left join users wu on wu.id = w.user_id
+	`, taskID, uid, req.Hours, date, note), &out); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// UpdateWorklog takes a merge patch; only the person who logged the time can
+// change it.
+func (h *Handler) UpdateWorklog(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	worklogID, ok := parseUUIDParam(c, "worklogId", "worklog")
+	if !ok {
+		return
+	}
+
+	var patch updateWorklogPatch
+	if err := decodeMergePatch(c.Request.Body, &patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
+		return
+	}
+
+	var hours *float64
+	if patch.Hours.Set {
+		if patch.Hours.Null || !validWorklogHours(patch.Hours.Value) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hours"})
+			return
+		}
+		hours = &patch.Hours.Value
+	}
+
+	var date *time.Time
+	if patch.Date.Set {
+		d, ok := parseWorklogDate(patch.Date.Value)
+		if patch.Date.Null || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date (want YYYY-MM-DD)"})
+			return
+		}
+		date = &d
+	}
+
+	var note *string
+	if patch.Note.Set {
+		n := strings.TrimSpace(patch.Note.Value) // null clears to ""
+		if len([]rune(n)) > maxWorklogNote {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "note too long"})
+			return
+		}
+		note = &n
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+	if err := taskInProject(ctx, h.DB, projectID, taskID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	var out Worklog
+	err := scanWorklog(h.DB.QueryRow(ctx, `
+		with updated as (
+			update task_worklogs
+			set
+				hours = coalesce($4, hours),
+				work_date = coalesce($5::date, work_date),
+				note = coalesce($6, note),
+				updated_at = now()
+			where id = $1 and task_id = $2 and user_id = $3::uuid
+			returning *
+		)
+		select `+worklogColumns+`
+		from updated w
+		left join users wu on wu.id = w.user_id
+	`, worklogID, taskID, uid, hours, date, note), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "worklog not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteWorklog is open to the person who logged the time and the owner.
+func (h *Handler) DeleteWorklog(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+	taskID, ok := parseUUIDParam(c, "taskId", "task")
+	if !ok {
+		return
+	}
+	worklogID, ok := parseUUIDParam(c, "worklogId", "worklog")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 5*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	cmd, err := h.DB.Exec(ctx, `
+		delete from task_worklogs w
+		using tasks t, projects p
+		where t.id = w.task_id
+			and p.id = t.project_id
+			and w.id = $1
+			and w.task_id = $2
+			and t.project_id = $3
+			and t.deleted_at is null
+			and (w.user_id = $4::uuid or p.owner_id = $4::uuid)
+	`, worklogID, taskID, projectID, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	if cmd.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "worklog not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GetWorklogTotals sums a project's logged hours, overall and per member and
+// task, for work dates in [from, to] (both optional, inclusive).
+func (h *Handler) GetWorklogTotals(c *gin.Context) {
+	uid, ok := getAuthUID(c)
+	if !ok {
+		return
+	}
+	projectID, ok := parseUUIDParam(c, "projectId", "project")
+	if !ok {
+		return
+	}
+
+	out := WorklogTotals{ByMember: []MemberHours{}, ByTask: []TaskHours{}}
+	var from, to *time.Time
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		d, ok := parseWorklogDate(v)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from (want YYYY-MM-DD)"})
+			return
+		}
+		from, out.From = &d, &v
+	}
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		d, ok := parseWorklogDate(v)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to (want YYYY-MM-DD)"})
+			return
+		}
+		to, out.To = &d, &v
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to is before from"})
+		return
+	}
+
+	ctx, cancel := contextTimeout(c, 8*time.Second)
+	defer cancel()
+
+	if !requireProjectMember(c, ctx, h.DB, projectID, uid) {
+		return
+	}
+
+	// One pass with grouping sets: the (user) rows, the (task) rows and the
+	// grand total row come back together.
+	rows, err := h.DB.Query(ctx, `
+		select
+			grouping(w.user_id) = 0,
+			grouping(w.task_id) = 0,
+			w.user_id::text,
+			max(wu.username),
+			w.task_id::text,
+			max(t.title),
+			max(t.estimate_hours)::float8,
+			coalesce(sum(w.hours), 0)::float8
+		from task_worklogs w
+		join tasks t on t.id = w.task_id
+		left join users wu on wu.id = w.user_id
+		where t.project_id = $1
+			and t.deleted_at is null
+			and ($2::date is null or w.work_date >= $2)
+			and ($3::date is null or w.work_date <= $3)
+		group by grouping sets ((w.user_id), (w.task_id), ())
+		order by 1, 2, 8 desc
+	`, projectID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var byUser, byTask bool
+		var userID, username, taskID, title *string
+		var estimate *float64
+		var hours float64
+		if err := rows.Scan(&byUser, &byTask, &userID, &username, &taskID, &title, &estimate, &hours); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			return
+		}
+		switch {
+		case byUser:
+			out.ByMember = append(out.ByMember, MemberHours{UserID: userID, Username: username, Hours: hours})
+		case byTask:
+			out.ByTask = append(out.ByTask, TaskHours{TaskID: *taskID, Title: *title, EstimateHours: estimate, Hours: hours})
+		default:
+			out.Hours = hours
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	root := t.TempDir()
+	l := &Local{Root: root}
+	tests := []struct {
+		key  string
+		want string // "" when the key is rejected
+	}{
+		{key: "tasks/abc/file.pdf", want: filepath.Join(root, "tasks", "abc", "file.pdf")},
+		{key: "/leading/slash", want: filepath.Join(root, "leading", "slash")},
+		{key: "a//b/./c", want: filepath.Join(root, "a", "b", "c")},
+		{key: "", want: ""},
+		{key: "/", want: ""},
+		{key: "..", want: ""},
+		{key: "../outside", want: ""},
+		{key: "tasks/../../outside", want: ""},
+		{key: "tasks/..", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := l.path(tt.key)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("path(%q) = %q, want an error", tt.key, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("path(%q): %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("path(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	l, err := NewLocal(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "tasks/abc/file.txt"
+
+	for _, body := range []string{"first", "second, longer"} {
+		if err := l.Put(ctx, key, strings.NewReader(body), int64(len(body)), "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", body, err)
+		}
+		if got := readBlob(t, l, key); got != body {
+			t.Errorf("Get = %q, want %q", got, body)
+		}
+	}
+	assertNoTempFiles(t, filepath.Join(l.Root, "tasks", "abc"))
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+// A failed Put must leave the previous object as it was and no temp file
+// behind.
+func TestLocalPutFailureKeepsObject(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		body io.Reader
+		size int64
+	}{
+		{name: "short body", ctx: context.Background(), body: strings.NewReader("abc"), size: 10},
+		{name: "long body", ctx: context.Background(), body: strings.NewReader("abcdefghijkl"), size: 10},
+		{name: "read error", ctx: context.Background(), body: io.MultiReader(strings.NewReader("abc"), errReader{}), size: -1},
+		{name: "cancelled", ctx: cancelled, body: strings.NewReader("abc"), size: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLocal(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Put(context.Background(), "k/blob", strings.NewReader("original"), 8, ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Put(tt.ctx, "k/blob", tt.body, tt.size, ""); err == nil {
+				t.Fatal("Put succeeded")
+			}
+			if got := readBlob(t, l, "k/blob"); got != "original" {
+				t.Errorf("Get = %q, want the original object", got)
+			}
+			assertNoTempFiles(t, filepath.Join(l.Root, "k"))
+		})
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := l.Put(ctx, "../escaped", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("Put accepted ../escaped")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file written outside the root: %v", err)
+	}
+	if _, err := l.Get(ctx, "../blobs/x"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(../blobs/x) = %v, want an invalid key error", err)
+	}
+	if err := l.Delete(ctx, "a/../../b"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(a/../../b) = %v, want an invalid key error", err)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func readBlob(t *testing.T, l *Local, key string) string {
+	t.Helper()
+	rc, err := l.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading %q: %v", key, err)
+	}
+	return string(b)
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".upload-") {
+			t.Errorf("temp file %s left in %s", e.Name(), dir)
+		}
+	}
+}
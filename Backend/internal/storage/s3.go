@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config points at an S3-compatible service (AWS, MinIO, ...). Requests use
+// path-style addressing, which every implementation accepts.
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 is a minimal object client signing requests with AWS Signature V4.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3: endpoint, bucket and credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	u, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	// No overall timeout: downloads stream for as long as the caller reads.
+	return &S3{cfg: cfg, endpoint: u, client: &http.Client{}}, nil
+}
+
+// Put streams the body without hashing it first (UNSIGNED-PAYLOAD), so S3
+// needs the exact length up front.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	key = strings.TrimLeft(key, "/")
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.cfg.Bucket + "/" + key
+	// Set the encoded form explicitly so the wire path matches the one signed.
+	u.RawPath = s.endpoint.Path + "/" + uriEncode(s.cfg.Bucket, false) + "/" + uriEncode(key, true)
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req, turning error statuses into errors. A 404 becomes
+// ErrNotFound.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+const (
+	sigAlgorithm    = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sign adds the SigV4 Authorization header. Only host, x-amz-date and
+// x-amz-content-sha256 are signed; that is all S3 requires.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	canonical, signedHeaders := canonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}, unsignedPayload)
+
+	scope := now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+	signature := hex.EncodeToString(hmacSHA256(
+		signingKey(s.cfg.SecretKey, now, s.cfg.Region, "s3"),
+		stringToSign(now, scope, canonical),
+	))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalRequest builds the SigV4 canonical request. path and query must
+// already be encoded; header names must be lower case and values trimmed.
+func canonicalRequest(method, path, query string, headers map[string]string, payloadHash string) (canonical, signedHeaders string) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonical = strings.Join([]string{
+		method,
+		path,
+		query,
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	return canonical, signedHeaders
+}
+
+func stringToSign(now time.Time, scope, canonical string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	return strings.Join([]string{sigAlgorithm, now.Format("20060102T150405Z"), scope, hex.EncodeToString(sum[:])}, "\n")
+}
+
+func signingKey(secret string, now time.Time, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), now.Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but RFC 3986 unreserved characters,
+// the way SigV4 expects. Slashes are kept when encoding an object key.
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && keepSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+// Package storage holds the blob stores used for task attachments.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Get and Delete when the key does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque objects by key. Keys are slash-separated and chosen
+// by the caller; stores must not interpret them beyond that.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object for streaming. The caller closes the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +15,7 @@ import (
 	"forge-api/internal/auth"
 	"forge-api/internal/db"
 	"forge-api/internal/handlers"
+	"forge-api/internal/storage"
 )
 
 func main() {
@@ -51,6 +54,60 @@ func main() {
 	// handlers
 	h := handlers.New(pool, []byte(cfg.JWTSecret))
 
+	// attachments: BLOB_BACKEND=local (default, files under BLOB_DIR) or s3
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("blob store setup failed: %v", err)
+	}
+	h.Blobs = blobs
+	if v := os.Getenv("ATTACHMENT_MAX_MB"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb <= 0 {
+			log.Fatalf("invalid ATTACHMENT_MAX_MB: %q", v)
+		}
+		h.MaxAttachmentBytes = mb << 20
+	}
+
+	// background jobs: respace task and project ranks that grew too long
+	rebalanceEvery := 10 * time.Minute
+	if v := os.Getenv("RANK_REBALANCE_MINUTES"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m <= 0 {
+			log.Fatalf("invalid RANK_REBALANCE_MINUTES: %q", v)
+		}
+		rebalanceEvery = time.Duration(m) * time.Minute
+	}
+	go h.RunRankRebalancer(context.Background(), rebalanceEvery)
+
+	// and create recurring tasks whose next date has arrived
+	recurEvery := 5 * time.Minute
+	if v := os.Getenv("RECURRENCE_MINUTES"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m <= 0 {
+			log.Fatalf("invalid RECURRENCE_MINUTES: %q", v)
+		}
+		recurEvery = time.Duration(m) * time.Minute
+	}
+	go h.RunRecurrenceScheduler(context.Background(), recurEvery)
+
+	// and purge deleted tasks once they have been in the trash long enough
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid TRASH_RETENTION_DAYS: %q", v)
+		}
+		h.TrashRetention = time.Duration(d) * 24 * time.Hour
+	}
+	purgeEvery := time.Hour
+	if v := os.Getenv("TRASH_PURGE_MINUTES"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m <= 0 {
+			log.Fatalf("invalid TRASH_PURGE_MINUTES: %q", v)
+		}
+		purgeEvery = time.Duration(m) * time.Minute
+	}
+	go h.RunTrashPurger(context.Background(), purgeEvery)
+
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{"ok": true})
 	})
@@ -84,11 +141,98 @@ func main() {
 	authed.DELETE("/projects/:projectId", h.DeleteProject)
 	authed.PATCH("/projects/:projectId/:pin", h.PinProject)
 	authed.PATCH("/projects/reorder", h.ReorderProjects)
+	authed.POST("/projects/:projectId/tags/:tagId", h.TagProject)
+	authed.DELETE("/projects/:projectId/tags/:tagId", h.UntagProject)
+	authed.GET("/projects/:projectId/workflow", h.GetWorkflow)
+	authed.PUT("/projects/:projectId/workflow", h.UpdateWorkflow)
+	authed.GET("/projects/:projectId/workflow/transitions", h.GetTransitions)
+	authed.PUT("/projects/:projectId/workflow/transitions", h.UpdateTransitions)
+	authed.PUT("/projects/:projectId/workflow/auto-block", h.UpdateAutoBlock)
 
 	// Project Tasks
 	authed.POST("/projects/:projectId/tasks", h.AddTask)
 	authed.PATCH("/projects/:projectId/tasks/:taskId", h.UpdateTask)
+	authed.POST("/projects/:projectId/tasks/:taskId/move", h.MoveTask)
+	authed.POST("/projects/:projectId/tasks/:taskId/copy", h.CopyTask)
 	authed.DELETE("/projects/:projectId/tasks/:taskId", h.DeleteTask)
+	authed.POST("/projects/:projectId/tasks/:taskId/restore", h.RestoreTask)
+	authed.GET("/projects/:projectId/trash", h.ListTrash)
+	// also reachable as POST /me/projects/:projectId/tasks:batch, see NoRoute below
+	authed.POST("/projects/:projectId/tasks/batch", h.BatchTasks)
+
+	// Task comments
+	authed.GET("/projects/:projectId/tasks/:taskId/comments", h.ListComments)
+	authed.POST("/projects/:projectId/tasks/:taskId/comments", h.CreateComment)
+	authed.PATCH("/projects/:projectId/tasks/:taskId/comments/:commentId", h.UpdateComment)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/comments/:commentId", h.DeleteComment)
+
+	// Task attachments
+	authed.GET("/projects/:projectId/tasks/:taskId/attachments", h.ListAttachments)
+	authed.POST("/projects/:projectId/tasks/:taskId/attachments", h.UploadAttachment)
+	authed.GET("/projects/:projectId/tasks/:taskId/attachments/:attachmentId", h.DownloadAttachment)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/attachments/:attachmentId", h.DeleteAttachment)
+
+	// Task checklists
+	authed.GET("/projects/:projectId/tasks/:taskId/checklist", h.ListChecklist)
+	authed.POST("/projects/:projectId/tasks/:taskId/checklist", h.AddChecklistItem)
+	authed.PATCH("/projects/:projectId/tasks/:taskId/checklist/reorder", h.ReorderChecklist)
+	authed.PATCH("/projects/:projectId/tasks/:taskId/checklist/:itemId", h.UpdateChecklistItem)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/checklist/:itemId", h.DeleteChecklistItem)
+
+	// Task dependencies
+	authed.GET("/projects/:projectId/tasks/:taskId/dependencies", h.GetTaskDependencies)
+	authed.POST("/projects/:projectId/tasks/:taskId/dependencies", h.AddTaskDependency)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/dependencies/:blockerId", h.DeleteTaskDependency)
+
+	// Task history
+	authed.GET("/projects/:projectId/tasks/:taskId/history", h.GetTaskHistory)
+
+	// Task watchers and mentions
+	authed.GET("/projects/:projectId/tasks/:taskId/watchers", h.ListWatchers)
+	authed.PUT("/projects/:projectId/tasks/:taskId/watch", h.WatchTask)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/watch", h.UnwatchTask)
+	authed.GET("/projects/:projectId/tasks/:taskId/mentions", h.ListMentions)
+
+	// Notifications
+	authed.GET("/notifications", h.ListNotifications)
+	authed.POST("/notifications/read", h.MarkNotificationsRead)
+
+	// Recurring tasks
+	authed.GET("/projects/:projectId/tasks/:taskId/recurrence", h.GetTaskRecurrence)
+	authed.PUT("/projects/:projectId/tasks/:taskId/recurrence", h.SetTaskRecurrence)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/recurrence", h.StopTaskRecurrence)
+	authed.PATCH("/projects/:projectId/tasks/:taskId/series", h.UpdateTaskSeries)
+
+	// Assignee suggestions
+	authed.POST("/projects/:projectId/tasks/:taskId/suggest-assignees", h.SuggestAssignees)
+
+	// Worklogs
+	authed.GET("/projects/:projectId/tasks/:taskId/worklogs", h.ListWorklogs)
+	authed.POST("/projects/:projectId/tasks/:taskId/worklogs", h.AddWorklog)
+	authed.PATCH("/projects/:projectId/tasks/:taskId/worklogs/:worklogId", h.UpdateWorklog)
+	authed.DELETE("/projects/:projectId/tasks/:taskId/worklogs/:worklogId", h.DeleteWorklog)
+	authed.GET("/projects/:projectId/worklogs/totals", h.GetWorklogTotals)
+
+	// My tasks
+	authed.GET("/tasks", h.ListMyTasks)
+	authed.GET("/tasks/due", h.GetMyDueTasks)
+	authed.GET("/tasks/search", h.SearchTasks)
+	authed.GET("/tasks/:taskKey", h.GetTaskByKey)
+
+	// Tags
+	authed.GET("/tags", h.ListTags)
+	authed.POST("/tags", h.CreateTag)
+	authed.PATCH("/tags/:tagId", h.UpdateTag)
+	authed.DELETE("/tags/:tagId", h.DeleteTag)
+
+	// Roadmap
+	authed.GET("/projects/:projectId/roadmap", h.GetRoadmap)
+	authed.GET("/projects/:projectId/epics", h.ListEpics)
+	authed.POST("/projects/:projectId/epics", h.CreateEpic)
+	authed.PATCH("/projects/:projectId/epics/:epicId", h.UpdateEpic)
+	authed.DELETE("/projects/:projectId/epics/:epicId", h.DeleteEpic)
+	authed.POST("/projects/:projectId/epics/:epicId/dependencies", h.AddEpicDependency)
+	authed.DELETE("/projects/:projectId/epics/:epicId/dependencies/:dependsOnId", h.DeleteEpicDependency)
 
 	// Project Members
 	/// user search
@@ -103,9 +247,42 @@ func main() {
 	authed.PATCH("/invites/:inviteId/cancel", h.CancelInvite)
 	authed.DELETE("/invites/:inviteId", h.DeleteInvite)
 
+	/// members
+	authed.DELETE("/projects/:projectId/members/:userId", h.RemoveProjectMember)
+
+	// gin can't route a colon inside a path segment, so the ":batch" spelling
+	// is rewritten to "/batch" and routed again.
+	r.NoRoute(func(c *gin.Context) {
+		if p := c.Request.URL.Path; strings.HasSuffix(p, "/tasks:batch") {
+			c.Request.URL.Path = strings.TrimSuffix(p, ":batch") + "/batch"
+			r.HandleContext(c)
+		}
+	})
+
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	fmt.Printf("%s Server running on http://localhost:%s\n", time.Now().Format("2006/01/02 15:04:05"), cfg.Port)
 	if err := r.Run(addr); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func newBlobStore() (storage.BlobStore, error) {
+	switch backend := os.Getenv("BLOB_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		return storage.NewLocal(dir)
+	case "s3":
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_BACKEND %q", backend)
+	}
+}